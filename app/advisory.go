@@ -0,0 +1,346 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	version "github.com/hashicorp/go-version"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Advisory is a known vulnerability of a range of versions of an
+// application.
+type Advisory struct {
+	ID         string   `yaml:"id" json:"id"`
+	Summary    string   `yaml:"summary" json:"summary,omitempty"`
+	Severity   string   `yaml:"severity" json:"severity,omitempty"`
+	Fixed      string   `yaml:"fixed" json:"fixed,omitempty"`
+	References []string `yaml:"references" json:"references,omitempty"`
+
+	// Affected is the range of affected versions, as version constraint
+	// like ">= 4.7.0, < 4.7.2"
+	Affected string `yaml:"affected" json:"affected,omitempty"`
+
+	ranges   []advisoryRange
+	versions []string
+}
+
+// advisoryRange is a range of affected versions, introduced is inclusive,
+// fixed exclusive and lastAffected inclusive. Empty bounds are unbounded.
+type advisoryRange struct {
+	introduced   string
+	fixed        string
+	lastAffected string
+}
+
+// Advisories contains the advisories per application key.
+type Advisories map[string][]*Advisory
+
+func compareVersion(v *version.Version, s string) (int, bool) {
+	if s == "" || s == "0" {
+		return 1, true
+	}
+
+	w, err := version.NewVersion(s)
+	if err != nil {
+		return 0, false
+	}
+
+	return v.Compare(w), true
+}
+
+func (r advisoryRange) contains(v *version.Version) bool {
+	if c, ok := compareVersion(v, r.introduced); !ok || c < 0 {
+		return false
+	}
+
+	if r.fixed == "" {
+	} else if c, ok := compareVersion(v, r.fixed); !ok || c >= 0 {
+		return false
+	}
+
+	if r.lastAffected == "" {
+	} else if c, ok := compareVersion(v, r.lastAffected); !ok || c > 0 {
+		return false
+	}
+
+	return true
+}
+
+// Affects returns true if the version s is affected by the advisory.
+func (a *Advisory) Affects(s string) bool {
+	for _, v := range a.versions {
+		if v == s {
+			return true
+		}
+	}
+
+	v, err := version.NewVersion(s)
+	if err != nil {
+		return false
+	}
+
+	if a.Affected != "" {
+		if c, err := version.NewConstraint(a.Affected); err == nil && c.Check(v) {
+			return true
+		}
+	}
+
+	for _, r := range a.ranges {
+		if r.contains(v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fixedIn returns the version fixing s, from the affected range containing
+// s, or otherwise the fixed version of the advisory.
+func (a *Advisory) fixedIn(s string) string {
+	v, err := version.NewVersion(s)
+	if err != nil {
+		return a.Fixed
+	}
+
+	for _, r := range a.ranges {
+		if r.fixed != "" && r.contains(v) {
+			return r.fixed
+		}
+	}
+
+	return a.Fixed
+}
+
+// For returns the advisories of the application key affecting version, with
+// the version fixing it.
+func (a Advisories) For(key string, version string) []*Advisory {
+	matches := []*Advisory{}
+
+	for _, advisory := range a[strings.ToLower(key)] {
+		if advisory.Affects(version) {
+			match := *advisory
+			match.Fixed = advisory.fixedIn(version)
+
+			matches = append(matches, &match)
+		}
+	}
+
+	return matches
+}
+
+type osvAdvisory struct {
+	ID       string `json:"id"`
+	Summary  string `json:"summary"`
+	Details  string `json:"details"`
+	Affected []struct {
+		Package struct {
+			Ecosystem string `json:"ecosystem"`
+			Name      string `json:"name"`
+		} `json:"package"`
+		Ranges []struct {
+			Type   string `json:"type"`
+			Events []struct {
+				Introduced   string `json:"introduced"`
+				Fixed        string `json:"fixed"`
+				LastAffected string `json:"last_affected"`
+			} `json:"events"`
+		} `json:"ranges"`
+		Versions []string `json:"versions"`
+	} `json:"affected"`
+	Severity []struct {
+		Type  string `json:"type"`
+		Score string `json:"score"`
+	} `json:"severity"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+	References []struct {
+		URL string `json:"url"`
+	} `json:"references"`
+}
+
+func (a Advisories) addOSV(data []byte) error {
+	var docs []osvAdvisory
+
+	// files contain a single advisory or a list of advisories
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "[") {
+		if err := json.Unmarshal(data, &docs); err != nil {
+			return err
+		}
+	} else {
+		var doc osvAdvisory
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+
+		docs = append(docs, doc)
+	}
+
+	for _, doc := range docs {
+		for _, affected := range doc.Affected {
+			advisory := &Advisory{
+				ID:       doc.ID,
+				Summary:  doc.Summary,
+				Severity: strings.ToLower(doc.DatabaseSpecific.Severity),
+				versions: affected.Versions,
+			}
+
+			if advisory.Summary == "" {
+				advisory.Summary = doc.Details
+			}
+
+			if advisory.Severity != "" {
+			} else if len(doc.Severity) > 0 {
+				advisory.Severity = doc.Severity[0].Score
+			}
+
+			for _, ref := range doc.References {
+				advisory.References = append(advisory.References, ref.URL)
+			}
+
+			for _, r := range affected.Ranges {
+				if r.Type == "GIT" {
+					continue
+				}
+
+				current := advisoryRange{}
+				for _, event := range r.Events {
+					if event.Introduced != "" {
+						current = advisoryRange{introduced: event.Introduced}
+					} else if event.Fixed != "" {
+						current.fixed = event.Fixed
+						advisory.ranges = append(advisory.ranges, current)
+						current = advisoryRange{}
+					} else if event.LastAffected != "" {
+						current.lastAffected = event.LastAffected
+						advisory.ranges = append(advisory.ranges, current)
+						current = advisoryRange{}
+					}
+				}
+
+				// introduced without fixed affects all later versions
+				if current.introduced != "" {
+					advisory.ranges = append(advisory.ranges, current)
+				}
+			}
+
+			key := strings.ToLower(affected.Package.Name)
+			a[key] = append(a[key], advisory)
+		}
+	}
+
+	return nil
+}
+
+func (a Advisories) addYAML(data []byte) error {
+	m := map[string][]*Advisory{}
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return err
+	}
+
+	for key, advisories := range m {
+		for _, advisory := range advisories {
+			if advisory.Affected == "" {
+			} else if _, err := version.NewConstraint(advisory.Affected); err != nil {
+				return fmt.Errorf("Invalid affected versions for %s: %s", advisory.ID, err.Error())
+			}
+		}
+
+		key = strings.ToLower(key)
+		a[key] = append(a[key], advisories...)
+	}
+
+	return nil
+}
+
+func (a Advisories) load(filename string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		err = a.addOSV(data)
+	case ".yaml", ".yml":
+		err = a.addYAML(data)
+	default:
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %s", filename, err.Error())
+	}
+
+	return nil
+}
+
+// LoadAdvisories loads advisories from a YAML file with affected ranges per
+// application key, a file with OSV advisories, or a directory with those
+// files.
+func LoadAdvisories(p string) (Advisories, error) {
+	a := Advisories{}
+
+	fi, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fi.IsDir() {
+		if err := a.load(p); err != nil {
+			return nil, err
+		}
+
+		return a, nil
+	}
+
+	err = filepath.Walk(p, func(name string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		} else if fi.IsDir() {
+			return nil
+		}
+
+		return a.load(name)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// printAdvisories prints the advisories of the best matching versions.
+func (b *identify) printAdvisories() {
+	reports := b.versionReports()
+	if len(reports) == 0 {
+		return
+	}
+
+	fmt.Fprintln(b.out, color.YellowString("[+] Advisories for the best matching versions: "))
+
+	found := false
+	for _, vr := range reports {
+		if vr.Matches != reports[0].Matches {
+			break
+		}
+
+		for _, advisory := range vr.Advisories {
+			found = true
+
+			fmt.Fprintln(b.out, color.RedString(" |  %s %s (%s, fixed in %s): %s", vr.Version, advisory.ID, advisory.Severity, advisory.Fixed, advisory.Summary))
+		}
+	}
+
+	if !found {
+		fmt.Fprintln(b.out, " |  No known advisories")
+	}
+
+	fmt.Fprintf(b.out, "\n")
+}
@@ -0,0 +1,102 @@
+package app
+
+import "testing"
+
+func TestAdvisoriesYAML(t *testing.T) {
+	a := Advisories{}
+
+	err := a.addYAML([]byte(`
+wordpress:
+  - id: CVE-2017-1001000
+    severity: high
+    affected: ">= 4.7.0, < 4.7.2"
+    fixed: "4.7.2"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]int{
+		"4.6.9": 0,
+		"4.7.0": 1,
+		"4.7.1": 1,
+		"4.7.2": 0,
+	}
+
+	for v, expected := range tests {
+		if advisories := a.For("WordPress", v); len(advisories) != expected {
+			t.Errorf("For(%s): expected %d advisories, got %d", v, expected, len(advisories))
+		}
+	}
+}
+
+func TestAdvisoriesOSV(t *testing.T) {
+	a := Advisories{}
+
+	err := a.addOSV([]byte(`{
+  "id": "OSV-1",
+  "summary": "SQL injection",
+  "affected": [{
+    "package": {"ecosystem": "Packagist", "name": "joomla"},
+    "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "3.0.0"}, {"fixed": "3.6.5"}, {"introduced": "3.7.0"}, {"last_affected": "3.7.1"}]}],
+    "versions": ["staging"]
+  }],
+  "database_specific": {"severity": "CRITICAL"}
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]int{
+		"2.5.0":   0,
+		"3.6.4":   1,
+		"3.6.5":   0,
+		"3.7.1":   1,
+		"3.7.2":   0,
+		"staging": 1,
+	}
+
+	for v, expected := range tests {
+		if advisories := a.For("joomla", v); len(advisories) != expected {
+			t.Errorf("For(%s): expected %d advisories, got %d", v, expected, len(advisories))
+		}
+	}
+
+	if advisories := a.For("joomla", "3.6.4"); advisories[0].Severity != "critical" {
+		t.Errorf("Expected severity critical, got %s", advisories[0].Severity)
+	}
+}
+
+func TestAdvisoriesOSVFixed(t *testing.T) {
+	a := Advisories{}
+
+	// each range is fixed in its own release
+	err := a.addOSV([]byte(`{
+  "id": "OSV-2",
+  "affected": [{
+    "package": {"ecosystem": "Packagist", "name": "joomla"},
+    "ranges": [
+      {"type": "ECOSYSTEM", "events": [{"introduced": "3.0.0"}, {"fixed": "3.6.5"}]},
+      {"type": "ECOSYSTEM", "events": [{"introduced": "3.7.0"}, {"fixed": "3.7.3"}]},
+      {"type": "ECOSYSTEM", "events": [{"introduced": "3.8.0"}, {"last_affected": "3.8.1"}]}
+    ]
+  }]
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"3.6.4": "3.6.5",
+		"3.7.1": "3.7.3",
+		"3.8.1": "",
+	}
+
+	for v, expected := range tests {
+		if advisories := a.For("joomla", v); len(advisories) != 1 {
+			t.Errorf("For(%s): expected 1 advisory, got %d", v, len(advisories))
+		} else if advisories[0].Fixed != expected {
+			t.Errorf("For(%s): expected fixed in %q, got %q", v, expected, advisories[0].Fixed)
+		}
+	}
+}
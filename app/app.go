@@ -1,7 +1,9 @@
 package app
 
 import (
+	"context"
 	"crypto/sha1"
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -13,18 +15,13 @@ import (
 	"path"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/cheggaaa/pb"
 	"github.com/fatih/color"
 	version "github.com/hashicorp/go-version"
 	_ "github.com/minio/cli"
 	_ "github.com/op/go-logging"
-	git "gopkg.in/src-d/go-git.v4"
-	"gopkg.in/src-d/go-git.v4/plumbing"
-	"gopkg.in/src-d/go-git.v4/plumbing/object"
-	"gopkg.in/src-d/go-git.v4/storage/filesystem"
-
-	"gopkg.in/src-d/go-billy.v2/osfs"
 
 	"bytes"
 
@@ -38,11 +35,28 @@ type identify struct {
 
 	client *http.Client
 
+	// transport is the transport to the network, with the proxy, used for
+	// the database and sources whatever the transport of the target is
+	transport http.RoundTripper
+
 	debug bool
 
 	hashes   map[string]*Result
 	versions []string
 
+	// skipped contains the files that have been requested but aren't
+	// hashed, like files that aren't found or have been redirected to
+	// another path
+	skipped map[string]*FileReport
+
+	commitMatches []*CommitMatch
+	differences   []*FileDifference
+
+	// component is set when identifying a component of the application,
+	// the results are added to components
+	component  *Component
+	components []*identify
+
 	application *Application
 	db          *DB
 
@@ -50,18 +64,60 @@ type identify struct {
 
 	proxyURL *url.URL
 
-	r *git.Repository
+	// rootfs is the directory the image has been flattened into
+	rootfs string
+
+	recorder *recorder
+
+	// bundleReport is set when the evidence bundle has been written
+	bundleReport *BundleReport
+
+	// dbHash is the sha-256 of the loaded database, revision the revision
+	// of the repository the versions have been retrieved from, at fetched
+	dbHash   string
+	revision string
+	fetched  time.Time
+
+	// addresses are the resolved addresses of the target
+	addresses []string
+
+	index   *Index
+	metrics *Metrics
+	hooks   *Hooks
+
+	ctx context.Context
+
+	out io.Writer
+
+	started  time.Time
+	finished time.Time
 }
 
+// FetchFn gets the url, like http.Get.
+type FetchFn func(u string) (*http.Response, error)
+
 func Download(src string, dest string) error {
-	resp, err := http.Get(src)
+	return download(http.Get, src, dest)
+}
+
+// download gets src using fetch and writes the body to dest.
+func download(fetch FetchFn, src string, dest string) error {
+	if fetch == nil {
+		fetch = http.Get
+	}
+
+	resp, err := fetch(src)
 	if err != nil {
 		return err
 	}
 
 	defer resp.Body.Close()
 
-	f, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY, 0600)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Error downloading %s got status code: %d", src, resp.StatusCode)
+	}
+
+	f, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
 	if err != nil {
 		return err
 	}
@@ -76,28 +132,48 @@ func Download(src string, dest string) error {
 	return nil
 }
 
+// DefaultCachePath returns the path of the database and the cached sources,
+// .identify in the home directory of the user.
+func DefaultCachePath() (string, error) {
+	usr, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+
+	return path.Join(usr.HomeDir, ".identify"), nil
+}
+
 func New(options ...OptionFn) (*identify, error) {
 	transport := &http.Transport{
 		Proxy: http.ProxyFromEnvironment,
 		Dial:  net.Dial,
 	}
 
-	cachePath := ""
-	if usr, err := user.Current(); err != nil {
+	cachePath, err := DefaultCachePath()
+	if err != nil {
 		return nil, err
-	} else {
-		cachePath = path.Join(usr.HomeDir, ".identify")
 	}
 
 	b := &identify{
 		client: &http.Client{
 			Transport: transport,
+			Timeout:   defaultTimeout,
 		},
+		transport: transport,
 		hashes:    map[string]*Result{},
 		versions:  []string{},
 		cachePath: cachePath,
+		out:       os.Stdout,
 	}
 
+	b.redirectPolicy = RedirectSameHost
+	b.maxRedirects = defaultMaxRedirects
+	b.retries = defaultRetries
+	b.backoff = defaultBackoff
+	b.maxBodySize = defaultMaxBodySize
+	b.maxCommits = defaultMaxCommits
+	b.client.CheckRedirect = b.checkRedirect
+
 	for _, optionFunc := range options {
 		if err := optionFunc(b); err != nil {
 			return nil, err
@@ -116,7 +192,7 @@ func New(options ...OptionFn) (*identify, error) {
 	if _, err := os.Stat(dbPath); err == nil {
 	} else if !os.IsNotExist(err) {
 		return nil, err
-	} else if err := Download("https://raw.githubusercontent.com/dutchcoders/identify/master/db.yaml", dbPath); err != nil {
+	} else if err := download(b.fetchSource, "https://raw.githubusercontent.com/dutchcoders/identify/master/db.yaml", dbPath); err != nil {
 		return nil, err
 	} else {
 	}
@@ -136,8 +212,11 @@ func New(options ...OptionFn) (*identify, error) {
 	}
 
 	b.db = &db
+	b.dbHash = fmt.Sprintf("%x", sha256.Sum256(data))
 
-	if application, ok := b.db.Application[b.targetApplication]; !ok {
+	if b.targetApplication == ApplicationAuto {
+		// detected when identifying
+	} else if application, ok := b.db.Application[b.targetApplication]; !ok {
 		return nil, fmt.Errorf("Application not found in rule set")
 	} else {
 		b.application = &application
@@ -152,79 +231,227 @@ func hashStr(s string) string {
 	return fmt.Sprintf("%x", h.Sum(nil))
 }
 
-func CalcHash(r io.ReadCloser) []byte {
+func CalcHash(r io.ReadCloser) ([]byte, error) {
+	defer r.Close()
+
 	h := sha1.New()
 
-	_, err := io.Copy(h, r)
-	if err != nil {
-		fmt.Println(err.Error())
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
 	}
 
-	r.Close()
-
-	return h.Sum(nil)
+	return h.Sum(nil), nil
 }
 
-func normalize(name plumbing.ReferenceName) string {
-	s := name.String()
-	s = strings.Replace(s, "refs/tags/", "", -1)
-	s = strings.Replace(s, "refs/heads/", "", -1)
-	return s
-}
+func (b *identify) WorkReference(ref Reference) error {
+	b.versions = append(b.versions, ref.Name())
 
-func (b *identify) WorkReference(ref *plumbing.Reference) error {
-	b.versions = append(b.versions, normalize(ref.Name()))
+	for fileName, hash := range b.hashes {
+		if ok, err := b.matches(ref, fileName, hash); err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
 
-	var tree *object.Tree
-	if c, err := b.r.CommitObject(ref.Hash()); err == nil {
-		tree, _ = c.Tree()
-	} else if c, err := b.r.TagObject(ref.Hash()); err == nil {
-		tree, _ = c.Tree()
-	} else if err != nil {
-		fmt.Println(color.RedString("Could not find commit or tag for %s: %s %s", ref.Name(), ref.Hash().String(), err.Error()))
-		return nil
+		hash.AddRef(ref)
 	}
 
-	if tree == nil {
-		return fmt.Errorf("Could not find tree for commit or tag")
+	return nil
+}
+
+// matches returns true if the file in ref has the same hash as the fetched
+// file.
+func (b *identify) matches(ref Reference, fileName string, hash *Result) (bool, error) {
+	h, err := b.hashFile(ref, path.Join(b.application.Root, fileName))
+	if err != nil {
+		return false, err
+	} else if h == nil {
+		return false, nil
 	}
 
+	return bytes.Compare(hash.Hash, h) == 0, nil
+}
+
+// matchesAll returns true if all fetched files match the files in ref.
+func (b *identify) matchesAll(ref Reference) (bool, error) {
 	for fileName, hash := range b.hashes {
-		f, err := tree.File(path.Join(b.application.Root, fileName))
-		if err != nil {
-			// verify error
-			continue
+		if ok, err := b.matches(ref, fileName, hash); err != nil {
+			return false, err
+		} else if !ok {
+			return false, nil
 		}
+	}
 
-		rdr, err := f.Reader()
-		if err != nil {
+	return true, nil
+}
+
+func (b *identify) workReferences(refs []Reference) error {
+	for _, ref := range refs {
+		if err := b.WorkReference(ref); err != nil {
 			return err
 		}
+	}
 
-		h := CalcHash(rdr)
-		if bytes.Compare(hash.Hash, h) != 0 {
-			continue
+	return nil
+}
+
+// result describes the outcome of the identification: failed, identified or
+// unidentified.
+func (b *identify) result(err error) string {
+	if err != nil {
+		return "failed"
+	}
+
+	for _, hash := range b.hashes {
+		if len(hash.Refs) > 0 {
+			return "identified"
 		}
+	}
 
-		hash.AddRef(ref)
+	return "unidentified"
+}
+
+func (b *identify) Identify() (err error) {
+	b.started = time.Now()
+	defer func() {
+		b.finished = time.Now()
+		b.metrics.inc(metricTargetsScanned, "application", b.targetApplication, "result", b.result(err))
+
+		if err == nil && b.application != nil {
+			b.hooks.NotifyReport(b.out, b.Report())
+		}
+	}()
+
+	if b.application != nil {
+		fmt.Fprintf(b.out, "| Application: %s\n", b.application.Name)
+	} else {
+		fmt.Fprintf(b.out, "| Application: %s\n", b.targetApplication)
+	}
+
+	if b.image != "" {
+		fmt.Fprintf(b.out, "| Image: %s\n", b.image)
+	} else {
+		fmt.Fprintf(b.out, "| Target URL: %s\n", b.targetURL.String())
+	}
+
+	if b.proxyURL != nil {
+		fmt.Fprintf(b.out, "| Using proxy: %s\n", b.proxyURL.String())
+	}
+
+	fmt.Fprintln(b.out)
+
+	if b.overallTimeout > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), b.overallTimeout)
+		defer cancel()
+
+		b.ctx = ctx
+	}
+
+	if b.image == "" {
+	} else if cleanup, err := b.openImage(); err != nil {
+		return err
+	} else {
+		defer cleanup()
+	}
+
+	if b.evidence == "" && b.bundle == "" {
+	} else if cleanup, err := b.record(); err != nil {
+		return err
+	} else {
+		defer cleanup()
+	}
+
+	if b.evidence != "" && b.image == "" {
+		// stored for the target url, before it is rebased
+		defer b.saveEvidence(b.targetURL.String())
+	}
+
+	if u, err := b.rebaseTargetURL(); err != nil {
+		fmt.Fprintln(b.out, color.RedString("[!] Could not request target url: %s", err.Error()))
+	} else if u != nil {
+		fmt.Fprintln(b.out, color.YellowString("[+] Target url redirects to %s, using it as base", u.String()))
+		b.targetURL = u
+	}
+
+	b.addresses = b.resolve()
+
+	if b.application != nil {
+	} else if name, err := b.detectApplication(); err != nil {
+		return err
+	} else {
+		application := b.db.Application[name]
+
+		b.targetApplication = name
+		b.application = &application
+	}
+
+	if err := b.identify(); err != nil {
+		return err
+	}
+
+	if !b.scanComponents {
+	} else if err := b.identifyComponents(); err != nil {
+		return err
+	}
+
+	if b.bundle != "" {
+		// the bundle contains the report, which is finished
+		b.finished = time.Now()
+
+		if err := b.writeBundle(); err != nil {
+			return fmt.Errorf("Could not write evidence bundle: %s", err.Error())
+		}
 	}
 
 	return nil
 }
 
-func (b *identify) Identify() error {
-	fmt.Printf("| Application: %s\n", b.application.Name)
-	fmt.Printf("| Target URL: %s\n", b.targetURL.String())
+// skipFile records the file that has been requested from u but isn't hashed,
+// with the reason. resp is nil when the request failed.
+func (b *identify) skipFile(file string, u *url.URL, resp *http.Response, reason string) {
+	if b.skipped == nil {
+		b.skipped = map[string]*FileReport{}
+	}
 
-	if b.proxyURL != nil {
-		fmt.Printf("| Using proxy: %s\n", b.proxyURL.String())
+	fr := &FileReport{
+		Path:     file,
+		URL:      u.String(),
+		Versions: []string{},
+		Skipped:  reason,
 	}
 
-	fmt.Println("")
+	if resp != nil {
+		fr.StatusCode = resp.StatusCode
+		fr.Redirects = redirectChain(resp)
+	}
+
+	b.skipped[file] = fr
+}
+
+// identify fetches the files of the application, and compares the hashes with
+// the versions of the source.
+func (b *identify) identify() error {
+	if b.started.IsZero() {
+		b.started = time.Now()
+	}
+
+	defer func() {
+		b.finished = time.Now()
+	}()
 
-	fmt.Println(color.YellowString("[+] Calculating hashes for remote files"))
+	fmt.Fprintln(b.out, color.YellowString("[+] Calculating hashes for remote files"))
+
+	fingerprints := map[string]*softNotFound{}
+
+	if b.noSoft404 {
+	} else if v, err := b.fingerprintNotFound(); err != nil {
+		fmt.Fprintln(b.out, color.RedString("[!] Could not fingerprint not found page: %s", err.Error()))
+	} else {
+		fingerprints = v
+	}
 
 	bar := pb.New(len(b.application.Files))
+	bar.Output = b.out
 	bar.SetWidth(40)
 	bar.SetMaxWidth(40)
 	bar.Format("[## ]")
@@ -236,95 +463,155 @@ func (b *identify) Identify() error {
 	for _, file := range b.application.Files {
 		rel, err := url.Parse(file)
 		if err != nil {
-			fmt.Println(color.RedString("[!] Could not parse url %s: %s", file, err.Error()))
+			fmt.Fprintln(b.out, color.RedString("[!] Could not parse url %s: %s", file, err.Error()))
 			continue
 		}
 
 		abs := b.targetURL.ResolveReference(rel)
 
-		resp, err := b.client.Get(abs.String())
+		resp, err := b.get(abs.String())
 		if err != nil {
-			fmt.Println(color.RedString("[!] Could not download url %s: %s", rel, err.Error()))
+			b.skipFile(file, abs, nil, err.Error())
+
+			fmt.Fprintln(b.out, color.RedString("[!] Could not download url %s: %s", rel, err.Error()))
 			continue
 		}
 
 		bar.Increment()
 
+		redirects := redirectChain(resp)
+
+		if len(redirects) > 0 && b.debug {
+			fmt.Fprintf(b.out, "[ ] Redirected %s\n", strings.Join(redirects, " -> "))
+		}
+
+		if redirectedElsewhere(resp, abs) {
+			closeBody(resp)
+
+			b.skipFile(file, abs, resp, "redirected to another path")
+
+			fmt.Fprintln(b.out, color.YellowString("[!] Ignoring %s, redirected to %s", abs.String(), resp.Request.URL.String()))
+			continue
+		}
+
 		if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
 		} else {
-			fmt.Println(color.RedString("[!] Error downloading %s got status code: %d", abs.String(), resp.StatusCode))
+			closeBody(resp)
+
+			b.skipFile(file, abs, resp, fmt.Sprintf("status code %d", resp.StatusCode))
+
+			if b.component == nil || b.debug {
+				fmt.Fprintln(b.out, color.RedString("[!] Error downloading %s got status code: %d", abs.String(), resp.StatusCode))
+			}
+
+			continue
+		}
+
+		body, err := readBody(resp, b.maxBodySize)
+		if err != nil {
+			b.skipFile(file, abs, resp, err.Error())
+
+			fmt.Fprintln(b.out, color.RedString("[!] Could not download url %s: %s", rel, err.Error()))
+			continue
+		}
+
+		if reason, ok := isSoftNotFound(fingerprints, file, resp, body); ok {
+			b.skipFile(file, abs, resp, "looks like a not found page: "+reason)
+
+			fmt.Fprintln(b.out, color.RedString("[!] Ignoring %s, looks like a not found page: %s", abs.String(), reason))
 			continue
 		}
 
-		hash := CalcHash(resp.Body)
+		hash, err := CalcHash(ioutil.NopCloser(bytes.NewReader(body)))
+		if err != nil {
+			fmt.Fprintln(b.out, color.RedString("[!] Could not calculate hash for %s: %s", rel, err.Error()))
+			continue
+		}
 
 		if b.debug {
-			fmt.Printf("[ ] Downloaded %s (%d): %x\n", abs.String(), resp.StatusCode, hash)
+			fmt.Fprintf(b.out, "[ ] Downloaded %s (%d): %x\n", abs.String(), resp.StatusCode, hash)
 		}
 
 		b.hashes[file] = &Result{
-			Hash: hash,
-			Refs: []*plumbing.Reference{},
+			Hash:       hash,
+			Refs:       []Reference{},
+			URL:        abs.String(),
+			StatusCode: resp.StatusCode,
+			Redirects:  redirects,
 		}
 	}
 
 	bar.Finish()
 
-	repoCachePath := path.Join(b.cachePath, hashStr(b.application.Repository))
+	if len(b.hashes) > 0 {
+	} else if b.component != nil {
+		fmt.Fprintln(b.out, " |  Not installed")
+		return nil
+	} else {
+		fmt.Fprintln(b.out, color.RedString("[!] Could not fetch any remote files"))
+		return nil
+	}
 
-	storage, err := filesystem.NewStorage(osfs.New(repoCachePath))
+	source, release, err := b.openSource()
 	if err != nil {
 		return err
 	}
 
-	fmt.Println(color.YellowString("[+] Cloning repository to cache"))
+	defer release()
 
-	r, err := git.Open(storage, nil)
-	if err == nil {
-	} else if err.Error() != "repository not exists" {
-		// unknown open error
-		return err
-	} else if r, err = git.Clone(storage, nil, &git.CloneOptions{
-		URL:      b.application.Repository,
-		Progress: os.Stdout,
-	}); err != nil {
-		return err
-	}
+	if is, ok := source.(*indexedSource); ok && is.fresh() {
+		b.fetched = is.updated
+	} else {
+		start := time.Now()
 
-	fmt.Println(color.YellowString("[+] Pulling latest changes from remote repository"))
+		if err := source.Update(); err != nil {
+			return err
+		}
+
+		sourceType := b.application.Type
+		if sourceType == "" {
+			sourceType = SourceGit
+		}
+
+		b.metrics.observe(metricSourceUpdate, time.Since(start).Seconds(), "type", sourceType)
 
-	err = r.Fetch(&git.FetchOptions{
-		Progress: os.Stdout,
-	})
-	if err == nil {
-	} else if err.Error() == "already up-to-date" {
-		fmt.Println(" |  Repository already up-to-date")
+		b.fetched = time.Now()
+	}
+
+	if r, ok := unwrapSource(source).(Revisioner); !ok {
+	} else if revision, err := r.Revision(); err != nil {
+		fmt.Fprintln(b.out, color.RedString("[!] Could not determine revision of repository: %s", err.Error()))
 	} else {
-		return err
+		b.revision = revision
 	}
 
-	b.r = r
+	branches := []Reference{}
+	tags := []Reference{}
+
+	start := time.Now()
 
 	if b.noBranches {
-	} else if ri, err := r.Branches(); err != nil {
+	} else if branches, err = source.Branches(); err != nil {
 		return err
-	} else if err := ri.ForEach(b.WorkReference); err != nil {
+	} else if err := b.workReferences(branches); err != nil {
 		return err
 	}
 
 	if b.noTags {
-	} else if ri, err := r.Tags(); err != nil {
+	} else if tags, err = source.Tags(); err != nil {
 		return err
-	} else if err := ri.ForEach(b.WorkReference); err != nil {
+	} else if err := b.workReferences(tags); err != nil {
 		return err
 	}
 
+	b.metrics.observe(metricIndexBuild, time.Since(start).Seconds(), "application", b.application.Name)
+
 	// convert refs to versions
-	Setify := func(refs []*plumbing.Reference) []string {
+	Setify := func(refs []Reference) []string {
 		vals := make([]string, len(refs))
 
 		for i, _ := range refs {
-			vals[i] = normalize(refs[i].Name())
+			vals[i] = refs[i].Name()
 		}
 
 		return vals
@@ -334,7 +621,11 @@ func (b *identify) Identify() error {
 		versions := Setify(hash.Refs)
 
 		if b.debug {
-			fmt.Printf("-> file: %s (%s): versions: %s\n", fileName, hex.EncodeToString(hash.Hash), strings.Join(versions, ", "))
+			fmt.Fprintf(b.out, "-> file: %s (%s): versions: %s\n", fileName, hex.EncodeToString(hash.Hash), strings.Join(versions, ", "))
+		}
+
+		if len(hash.Redirects) > 0 {
+			fmt.Fprintln(b.out, color.YellowString(" |  %s has been redirected: %s", fileName, strings.Join(hash.Redirects, " -> ")))
 		}
 	}
 
@@ -343,7 +634,7 @@ func (b *identify) Identify() error {
 
 	for _, hash := range b.hashes {
 		for _, ref := range hash.Refs {
-			version := normalize(ref.Name())
+			version := ref.Name()
 
 			if _, ok := counts[version]; !ok {
 				counts[version] = 0
@@ -354,7 +645,7 @@ func (b *identify) Identify() error {
 	}
 
 	if len(counts) == 0 {
-		fmt.Println(color.RedString("Could not identify web application"))
+		fmt.Fprintln(b.out, color.RedString("Could not identify web application"))
 		return nil
 	}
 
@@ -365,7 +656,7 @@ func (b *identify) Identify() error {
 		for i, raw := range versionsRaw {
 			v, err := version.NewVersion(raw)
 			if err != nil {
-				fmt.Println(color.RedString("Could not identify version: %s: %s", raw, err.Error()))
+				fmt.Fprintln(b.out, color.RedString("Could not identify version: %s: %s", raw, err.Error()))
 				continue
 			}
 
@@ -375,10 +666,10 @@ func (b *identify) Identify() error {
 		sort.Sort(version.Collection(versions))
 
 	*/
-	fmt.Printf("\n")
+	fmt.Fprintf(b.out, "\n")
 
 	// print identification summary
-	fmt.Printf(color.GreenString("[+] Web application has been identified as one of the following versions: \n"))
+	fmt.Fprintln(b.out, color.GreenString("[+] Web application has been identified as one of the following versions: "))
 
 	n := map[int][]string{}
 
@@ -405,9 +696,26 @@ func (b *identify) Identify() error {
 			}
 		}
 
-		fmt.Println(color.GreenString(" |  %3.0f%% %s", ((float64(k) * 100) / float64(len(b.application.Files))), strings.Join(s2, ", ")))
+		fmt.Fprintln(b.out, color.GreenString(" |  %3.0f%% %s", ((float64(k)*100)/float64(len(b.application.Files))), strings.Join(s2, ", ")))
+	}
+
+	fmt.Fprintf(b.out, "\n")
+
+	if b.advisories != nil {
+		b.printAdvisories()
+	}
+
+	if !b.integrity && !(b.allFiles && isLocal(b.targetURL)) {
+	} else if err := b.compareFiles(append(branches, tags...), fingerprints); err != nil {
+		return err
+	}
+
+	if !b.commits {
+	} else if gs, ok := unwrapSource(source).(*gitSource); !ok {
+		fmt.Fprintln(b.out, color.RedString("[!] Commit identification is only supported for git repositories"))
+	} else if err := b.identifyCommits(gs, unwrapReferences(branches), unwrapReferences(tags), n[a[0]]); err != nil {
+		return err
 	}
 
-	fmt.Printf("\n")
 	return nil
 }
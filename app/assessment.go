@@ -0,0 +1,162 @@
+package app
+
+import (
+	htmltemplate "html/template"
+	"io"
+	"text/template"
+)
+
+const htmlAssessment = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Identify assessment report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #222; margin: 2em auto; max-width: 1100px; padding: 0 1em; }
+h1 { border-bottom: 2px solid #222; padding-bottom: .3em; }
+h2 { margin-top: 2em; border-bottom: 1px solid #ccc; padding-bottom: .2em; }
+table { border-collapse: collapse; width: 100%; margin: 1em 0; font-size: 90%; }
+th, td { border: 1px solid #ddd; padding: .4em .6em; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; }
+code { font-family: Menlo, Consolas, monospace; font-size: 90%; word-break: break-all; }
+.best { background: #e8f5e9; }
+.warning { color: #b71c1c; }
+.muted { color: #777; }
+dl { display: grid; grid-template-columns: max-content auto; gap: .3em 1em; }
+dt { font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>Identify assessment report</h1>
+<p class="muted">Generated {{ date now }}, {{ len . }} target(s).</p>
+{{ range . }}{{ template "report" . }}{{ end }}
+</body>
+</html>
+{{ define "report" }}
+<h2>{{ if .Component }}{{ .Component.Kind }} {{ end }}{{ .Application }} &mdash; {{ .Target }}</h2>
+<dl>
+<dt>Target</dt><dd><code>{{ .Target }}</code></dd>
+<dt>Application</dt><dd>{{ .Application }}</dd>
+{{ with .Best }}<dt>Identified version</dt><dd>{{ .Version }} ({{ percent .Score }} of files)</dd>{{ else }}<dt>Identified version</dt><dd class="warning">Could not identify version</dd>{{ end }}
+{{ if .Latest }}<dt>Latest release</dt><dd>{{ .Latest }}{{ if .Outdated }} <span class="warning">(outdated)</span>{{ end }}</dd>{{ end }}
+<dt>Started</dt><dd>{{ date .Started }}</dd>
+<dt>Duration</dt><dd>{{ .Duration }}</dd>
+{{ with .Provenance }}{{ if .ToolVersion }}<dt>Identify version</dt><dd>{{ .ToolVersion }}</dd>{{ end }}
+<dt>Database</dt><dd><code>{{ .Database }}</code></dd>
+<dt>Repository</dt><dd><code>{{ .Repository }}</code>{{ if .Revision }} at <code>{{ .Revision }}</code>{{ end }}{{ with .Fetched }}, fetched {{ date . }}{{ end }}</dd>
+{{ if .Addresses }}<dt>Addresses</dt><dd>{{ join .Addresses ", " }}</dd>{{ end }}{{ end }}
+{{ with .Bundle }}<dt>Evidence bundle</dt><dd><code>{{ .File }}</code>, SHA-256 <code>{{ .SHA256 }}</code></dd>{{ end }}
+</dl>
+{{ if .Versions }}
+<h3>Candidate versions</h3>
+<table>
+<tr><th>Version</th><th>Score</th><th>Files</th><th>CPE / purl</th><th>Advisories</th></tr>
+{{ $best := .Best }}{{ range .Versions }}<tr{{ if eq .Matches $best.Matches }} class="best"{{ end }}>
+<td>{{ .Version }}</td><td>{{ percent .Score }}</td><td>{{ .Matches }}</td>
+<td>{{ if .CPE }}<code>{{ .CPE }}</code><br>{{ end }}{{ if .Purl }}<code>{{ .Purl }}</code>{{ end }}</td>
+<td>{{ range .Advisories }}<span class="warning">{{ .ID }}</span> {{ .Severity }}{{ if .Fixed }}, fixed in {{ .Fixed }}{{ end }}<br>{{ end }}</td>
+</tr>
+{{ end }}</table>
+{{ end }}
+{{ if .Commits }}
+<h3>Matching commits</h3>
+<table>
+<tr><th>Commit</th><th>Date</th><th>After tag</th><th>Before tag</th></tr>
+{{ range .Commits }}<tr><td><code>{{ .Hash }}</code></td><td>{{ date .Date }}</td><td>{{ .PreviousTag }}</td><td>{{ .NextTag }}</td></tr>
+{{ end }}</table>
+{{ end }}
+<h3>Evidence</h3>
+<table>
+<tr><th>File</th><th>Status</th><th>SHA-1</th><th>Matching versions</th></tr>
+{{ range .Files }}<tr>
+<td><a href="{{ .URL }}">{{ .Path }}</a>{{ if .Redirects }}<br><span class="muted">redirected: {{ join .Redirects " → " }}</span>{{ end }}</td>
+<td>{{ .StatusCode }}</td><td><code>{{ .Hash }}</code></td>
+<td>{{ if .Versions }}{{ join .Versions ", " }}{{ else if .Skipped }}<span class="muted">{{ .Skipped }}</span>{{ else }}<span class="warning">no known version</span>{{ end }}</td>
+</tr>
+{{ else }}<tr><td colspan="4" class="muted">No files could be fetched</td></tr>
+{{ end }}</table>
+{{ if .Differences }}
+<h3>Differences</h3>
+<table>
+<tr><th>File</th><th>Status</th><th>SHA-1</th></tr>
+{{ range .Differences }}<tr><td>{{ .Path }}</td><td class="warning">{{ .Status }}</td><td><code>{{ .Hash }}</code></td></tr>
+{{ end }}</table>
+{{ end }}{{ range .Components }}{{ template "report" . }}{{ end }}
+{{ end }}`
+
+const markdownAssessment = `# Identify assessment report
+
+Generated {{ date now }}, {{ len . }} target(s).
+{{ range . }}{{ template "report" . }}{{ end }}
+{{- define "report" }}
+## {{ if .Component }}{{ .Component.Kind }} {{ end }}{{ md .Application }} — {{ .Target }}
+
+* **Target:** {{ .Target }}
+* **Application:** {{ md .Application }}
+{{- with .Best }}
+* **Identified version:** {{ md .Version }} ({{ percent .Score }} of files)
+{{- else }}
+* **Identified version:** could not identify version
+{{- end }}
+{{- if .Latest }}
+* **Latest release:** {{ md .Latest }}{{ if .Outdated }} (outdated){{ end }}
+{{- end }}
+* **Started:** {{ date .Started }}
+* **Duration:** {{ .Duration }}
+{{- with .Provenance }}
+{{- if .ToolVersion }}
+* **Identify version:** {{ .ToolVersion }}
+{{- end }}
+* **Database:** ` + "`{{ .Database }}`" + `
+* **Repository:** {{ .Repository }}{{ if .Revision }} at ` + "`{{ .Revision }}`" + `{{ end }}{{ with .Fetched }}, fetched {{ date . }}{{ end }}
+{{- if .Addresses }}
+* **Addresses:** {{ join .Addresses ", " }}
+{{- end }}
+{{- end }}
+{{- with .Bundle }}
+* **Evidence bundle:** {{ md .File }}, SHA-256 ` + "`{{ .SHA256 }}`" + `
+{{- end }}
+{{ if .Versions }}
+### Candidate versions
+
+| Version | Score | Files | CPE | purl | Advisories |
+| --- | --- | --- | --- | --- | --- |
+{{ range .Versions }}| {{ md .Version }} | {{ percent .Score }} | {{ .Matches }} | {{ if .CPE }}` + "`{{ .CPE }}`" + `{{ end }} | {{ if .Purl }}` + "`{{ .Purl }}`" + `{{ end }} | {{ range .Advisories }}{{ md .ID }} ({{ md .Severity }}{{ if .Fixed }}, fixed in {{ md .Fixed }}{{ end }}) {{ end }}|
+{{ end }}{{ end }}
+{{- if .Commits }}
+### Matching commits
+
+| Commit | Date | After tag | Before tag |
+| --- | --- | --- | --- |
+{{ range .Commits }}| ` + "`{{ .Hash }}`" + ` | {{ date .Date }} | {{ md .PreviousTag }} | {{ md .NextTag }} |
+{{ end }}{{ end }}
+### Evidence
+
+| File | Status | SHA-1 | Matching versions |
+| --- | --- | --- | --- |
+{{ range .Files }}| {{ md .Path }}{{ if .Redirects }} (redirected: {{ md (join .Redirects " -> ") }}){{ end }} | {{ .StatusCode }} | ` + "`{{ .Hash }}`" + ` | {{ if .Versions }}{{ md (join .Versions ", ") }}{{ else if .Skipped }}{{ md .Skipped }}{{ else }}**no known version**{{ end }} |
+{{ end }}{{ if .Differences }}
+### Differences
+
+| File | Status | SHA-1 |
+| --- | --- | --- |
+{{ range .Differences }}| {{ md .Path }} | {{ .Status }} | ` + "`{{ .Hash }}`" + ` |
+{{ end }}{{ end }}
+{{- range .Components }}{{ template "report" . }}{{ end }}
+{{- end }}`
+
+var (
+	htmlAssessmentTemplate     = htmltemplate.Must(htmltemplate.New("assessment").Funcs(templateFuncs).Parse(htmlAssessment))
+	markdownAssessmentTemplate = template.Must(template.New("assessment").Funcs(templateFuncs).Parse(markdownAssessment))
+)
+
+// WriteHTML writes a self-contained html assessment report of the reports
+// to w.
+func WriteHTML(w io.Writer, reports ...*Report) error {
+	return htmlAssessmentTemplate.Execute(w, reports)
+}
+
+// WriteMarkdown writes a markdown assessment report of the reports to w.
+func WriteMarkdown(w io.Writer, reports ...*Report) error {
+	return markdownAssessmentTemplate.Execute(w, reports)
+}
@@ -0,0 +1,192 @@
+package app
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// bundleResponse is a recorded response in the manifest of a bundle, the
+// headers and body are separate files in the bundle.
+type bundleResponse struct {
+	URL        string    `json:"url"`
+	StatusCode int       `json:"status_code"`
+	Time       time.Time `json:"time"`
+	Headers    string    `json:"headers"`
+	Body       string    `json:"body"`
+	Size       int64     `json:"size"`
+	SHA1       string    `json:"sha1"`
+	SHA256     string    `json:"sha256"`
+}
+
+// bundleManifest describes the contents of a bundle, with the provenance of
+// the result.
+type bundleManifest struct {
+	Created     time.Time `json:"created"`
+	Target      string    `json:"target"`
+	Application string    `json:"application"`
+
+	Provenance *Provenance `json:"provenance"`
+
+	Version string  `json:"version,omitempty"`
+	Score   float64 `json:"score,omitempty"`
+
+	Report    string            `json:"report"`
+	Responses []*bundleResponse `json:"responses"`
+}
+
+// bundleWriter writes files to a tar archive, keeping the sha-256 of every
+// file for the checksums.
+type bundleWriter struct {
+	tw      *tar.Writer
+	modTime time.Time
+
+	checksums bytes.Buffer
+}
+
+func (w *bundleWriter) add(name string, data []byte) error {
+	if err := w.tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(data)),
+		ModTime:  w.modTime,
+		Typeflag: tar.TypeReg,
+	}); err != nil {
+		return err
+	} else if _, err := w.tw.Write(data); err != nil {
+		return err
+	}
+
+	// sha256sum format
+	fmt.Fprintf(&w.checksums, "%x  %s\n", sha256.Sum256(data), name)
+	return nil
+}
+
+// WriteBundle writes an evidence bundle of the identification to w, a gzip
+// compressed tar archive with the recorded responses, their headers and
+// bodies as separate files, the report and a manifest. The SHA256SUMS file
+// contains the checksums of all other files of the bundle.
+func (b *identify) WriteBundle(w io.Writer) error {
+	if b.recorder == nil {
+		return fmt.Errorf("Responses haven't been recorded")
+	}
+
+	report := b.Report()
+
+	manifest := &bundleManifest{
+		Created:     time.Now().UTC(),
+		Target:      report.Target,
+		Application: report.Application,
+		Provenance:  report.Provenance,
+		Report:      "report.json",
+		Responses:   []*bundleResponse{},
+	}
+
+	if best := report.Best(); best != nil {
+		manifest.Version = best.Version
+		manifest.Score = best.Score
+	}
+
+	gw := gzip.NewWriter(w)
+	bw := &bundleWriter{
+		tw:      tar.NewWriter(gw),
+		modTime: manifest.Created,
+	}
+
+	for i, response := range b.recorder.Responses() {
+		r := &bundleResponse{
+			URL:        response.URL,
+			StatusCode: response.StatusCode,
+			Time:       response.Time,
+			Headers:    fmt.Sprintf("responses/%04d.headers", i+1),
+			Body:       fmt.Sprintf("responses/%04d.body", i+1),
+			Size:       response.Size,
+			SHA1:       response.SHA1,
+			SHA256:     response.SHA256,
+		}
+
+		headers, body, err := b.recorder.read(response)
+		if err != nil {
+			return err
+		}
+
+		if err := bw.add(r.Headers, headers); err != nil {
+			return err
+		} else if err := bw.add(r.Body, body); err != nil {
+			return err
+		}
+
+		manifest.Responses = append(manifest.Responses, r)
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	} else if err := bw.add(manifest.Report, data); err != nil {
+		return err
+	}
+
+	if data, err = json.MarshalIndent(manifest, "", "  "); err != nil {
+		return err
+	} else if err := bw.add("manifest.json", data); err != nil {
+		return err
+	}
+
+	checksums := append([]byte{}, bw.checksums.Bytes()...)
+	if err := bw.add("SHA256SUMS", checksums); err != nil {
+		return err
+	}
+
+	if err := bw.tw.Close(); err != nil {
+		return err
+	}
+
+	return gw.Close()
+}
+
+// writeBundle writes the evidence bundle to the bundle file, and its sha-256
+// to the bundle file with .sha256 appended, in sha256sum format.
+func (b *identify) writeBundle() error {
+	f, err := os.OpenFile(b.bundle, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	h := sha256.New()
+
+	if err := b.WriteBundle(io.MultiWriter(f, h)); err != nil {
+		return err
+	} else if err := f.Close(); err != nil {
+		return err
+	}
+
+	checksum := fmt.Sprintf("%x", h.Sum(nil))
+
+	// named relative to the sidecar, to be checked in the directory of the
+	// bundle
+	sidecar := fmt.Sprintf("%s  %s\n", checksum, filepath.Base(b.bundle))
+	if err := ioutil.WriteFile(b.bundle+".sha256", []byte(sidecar), 0600); err != nil {
+		return err
+	}
+
+	b.bundleReport = &BundleReport{
+		File:   b.bundle,
+		SHA256: checksum,
+	}
+
+	fmt.Fprintln(b.out, color.YellowString("[+] Wrote evidence bundle %s", b.bundle))
+	fmt.Fprintf(b.out, " |  SHA-256: %s, written to %s.sha256\n", checksum, b.bundle)
+	return nil
+}
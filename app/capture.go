@@ -0,0 +1,356 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// capturedResponse is a response captured by another tool.
+type capturedResponse struct {
+	code   int
+	header http.Header
+	body   []byte
+}
+
+// captureTransport serves responses from captured traffic instead of the
+// network: a HAR file, a WARC file or a directory mirrored with wget.
+// Requests for urls that haven't been captured are returned as 404.
+type captureTransport struct {
+	responses map[string]*capturedResponse
+
+	// dir is the directory of a mirror
+	dir string
+}
+
+// captureKey returns the normalized url, used to match requests with
+// captured responses.
+func captureKey(u *url.URL) string {
+	host := strings.ToLower(u.Host)
+	if strings.HasSuffix(host, ":80") && u.Scheme == "http" {
+		host = strings.TrimSuffix(host, ":80")
+	} else if strings.HasSuffix(host, ":443") && u.Scheme == "https" {
+		host = strings.TrimSuffix(host, ":443")
+	}
+
+	p := u.EscapedPath()
+	if p == "" {
+		p = "/"
+	}
+
+	key := strings.ToLower(u.Scheme) + "://" + host + p
+	if u.RawQuery != "" {
+		key += "?" + u.RawQuery
+	}
+
+	return key
+}
+
+// add adds the response captured for rawurl. Revalidated responses don't
+// contain a body and are ignored, as are responses that have already been
+// captured successfully.
+func (t *captureTransport) add(rawurl string, response *capturedResponse) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return
+	}
+
+	if response.code == http.StatusNotModified {
+		return
+	}
+
+	key := captureKey(u)
+	if existing, ok := t.responses[key]; ok && existing.code/100 == 2 {
+		return
+	}
+
+	// bodies have been decoded already
+	response.header.Del("Content-Encoding")
+	response.header.Del("Content-Length")
+	response.header.Del("Transfer-Encoding")
+
+	t.responses[key] = response
+}
+
+type harLog struct {
+	Log struct {
+		Entries []struct {
+			Request struct {
+				URL string `json:"url"`
+			} `json:"request"`
+			Response struct {
+				Status  int `json:"status"`
+				Headers []struct {
+					Name  string `json:"name"`
+					Value string `json:"value"`
+				} `json:"headers"`
+				Content struct {
+					Size     int64  `json:"size"`
+					Text     string `json:"text"`
+					Encoding string `json:"encoding"`
+				} `json:"content"`
+			} `json:"response"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// readHAR adds the responses of the HAR file. Responses of which the body
+// hasn't been saved are skipped.
+func (t *captureTransport) readHAR(r io.Reader) error {
+	var har harLog
+	if err := json.NewDecoder(r).Decode(&har); err != nil {
+		return err
+	}
+
+	for _, entry := range har.Log.Entries {
+		content := entry.Response.Content
+		if content.Text == "" && content.Size > 0 {
+			continue
+		}
+
+		body := []byte(content.Text)
+		if content.Encoding == "base64" {
+			data, err := base64.StdEncoding.DecodeString(content.Text)
+			if err != nil {
+				return fmt.Errorf("Invalid content of %s: %s", entry.Request.URL, err.Error())
+			}
+
+			body = data
+		}
+
+		header := http.Header{}
+		for _, h := range entry.Response.Headers {
+			header.Add(h.Name, h.Value)
+		}
+
+		t.add(entry.Request.URL, &capturedResponse{
+			code:   entry.Response.Status,
+			header: header,
+			body:   body,
+		})
+	}
+
+	return nil
+}
+
+// readWARC adds the response records of the WARC file.
+func (t *captureTransport) readWARC(r io.Reader) error {
+	br := bufio.NewReader(r)
+	tp := textproto.NewReader(br)
+
+	for {
+		line, err := tp.ReadLine()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		} else if line == "" {
+			continue
+		} else if !strings.HasPrefix(line, "WARC/") {
+			return fmt.Errorf("Invalid WARC record: %s", line)
+		}
+
+		header, err := tp.ReadMIMEHeader()
+		if err != nil {
+			return err
+		}
+
+		length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+		if err != nil {
+			return fmt.Errorf("Invalid WARC record length: %s", header.Get("Content-Length"))
+		}
+
+		block := io.LimitReader(br, length)
+
+		if header.Get("WARC-Type") == "response" && strings.HasPrefix(header.Get("Content-Type"), "application/http") {
+			resp, err := http.ReadResponse(bufio.NewReader(block), nil)
+			if err != nil {
+				return err
+			}
+
+			var body io.Reader = resp.Body
+			if resp.Header.Get("Content-Encoding") == "gzip" {
+				if body, err = gzip.NewReader(resp.Body); err != nil {
+					return err
+				}
+			}
+
+			// bodies larger than the maximum size are recorded truncated,
+			// shorter than their Content-Length
+			data, err := ioutil.ReadAll(body)
+			if err != nil && err != io.ErrUnexpectedEOF {
+				return err
+			}
+
+			resp.Body.Close()
+
+			t.add(strings.Trim(header.Get("WARC-Target-URI"), "<>"), &capturedResponse{
+				code:   resp.StatusCode,
+				header: resp.Header,
+				body:   data,
+			})
+		}
+
+		if _, err := io.Copy(ioutil.Discard, block); err != nil {
+			return err
+		}
+	}
+}
+
+// mirrored returns the file of the url in the mirror, with or without a
+// directory for the host, as created by wget.
+func (t *captureTransport) mirrored(u *url.URL) (string, os.FileInfo) {
+	p := path.Clean("/" + u.Path)
+	if strings.HasSuffix(u.Path, "/") || u.Path == "" {
+		p = path.Join(p, "index.html")
+	}
+
+	names := []string{}
+	for _, prefix := range []string{u.Host, u.Hostname(), ""} {
+		name := filepath.Join(t.dir, prefix, filepath.FromSlash(p))
+		if u.RawQuery != "" {
+			names = append(names, name+"?"+u.RawQuery)
+		}
+
+		names = append(names, name)
+	}
+
+	for _, name := range names {
+		if fi, err := os.Stat(name); err == nil && fi.Mode().IsRegular() {
+			return name, fi
+		}
+	}
+
+	return "", nil
+}
+
+func (t *captureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if response, ok := t.responses[captureKey(req.URL)]; ok {
+		header := http.Header{}
+		for k, v := range response.header {
+			header[k] = v
+		}
+
+		return newResponse(req, response.code, header, ioutil.NopCloser(bytes.NewReader(response.body)), int64(len(response.body))), nil
+	}
+
+	if t.dir == "" {
+		return newResponse(req, http.StatusNotFound, nil, nil, 0), nil
+	}
+
+	name, fi := t.mirrored(req.URL)
+	if name == "" {
+		return newResponse(req, http.StatusNotFound, nil, nil, 0), nil
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	if contentType := mime.TypeByExtension(path.Ext(req.URL.Path)); contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+
+	return newResponse(req, http.StatusOK, header, f, fi.Size()), nil
+}
+
+// isCaptureFile returns true if name is a HAR or WARC file.
+func isCaptureFile(name string) bool {
+	name = strings.ToLower(name)
+
+	for _, ext := range []string{".har", ".warc", ".warc.gz"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// openCapture reads the captured traffic at location, a HAR file, a WARC
+// file, optionally gzip compressed, or a directory of fetched responses.
+// The responses in a directory are either files laid out like a wget mirror,
+// or HAR and WARC files in the directory itself.
+func openCapture(location string) (*captureTransport, error) {
+	t := &captureTransport{
+		responses: map[string]*capturedResponse{},
+	}
+
+	fi, err := os.Stat(location)
+	if err != nil {
+		return nil, err
+	} else if fi.IsDir() {
+	} else if err := t.read(location); err != nil {
+		return nil, err
+	} else {
+		return t, nil
+	}
+
+	t.dir = location
+
+	files, err := ioutil.ReadDir(location)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fi := range files {
+		if !fi.Mode().IsRegular() || !isCaptureFile(fi.Name()) {
+			continue
+		} else if err := t.read(filepath.Join(location, fi.Name())); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// read adds the responses of the HAR or WARC file, optionally gzip
+// compressed.
+func (t *captureTransport) read(location string) error {
+	f, err := os.Open(location)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+
+	var r io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+
+		r = bufio.NewReader(gr)
+	}
+
+	if magic, err := r.(*bufio.Reader).Peek(5); err == nil && string(magic) == "WARC/" {
+		err = t.readWARC(r)
+	} else {
+		err = t.readHAR(r)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Could not read capture %s: %s", location, err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package app
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+const testHAR = `{"log": {"entries": [
+	{"request": {"url": "https://example.com/readme.txt"}, "response": {"status": 200, "headers": [{"name": "Content-Type", "value": "text/plain"}], "content": {"size": 5, "text": "1.1.0"}}},
+	{"request": {"url": "https://example.com/readme.txt"}, "response": {"status": 304, "headers": [], "content": {"size": 0}}},
+	{"request": {"url": "https://example.com:443/css/style.css?ver=1"}, "response": {"status": 200, "headers": [], "content": {"size": 7, "text": "Ym9keSB7fQ==", "encoding": "base64"}}},
+	{"request": {"url": "https://example.com/logo.png"}, "response": {"status": 200, "headers": [], "content": {"size": 1024}}}
+]}}`
+
+const testHTTPResponse = "HTTP/1.1 200 OK\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"Content-Length: 5\r\n" +
+	"\r\n" +
+	"1.1.0"
+
+var testWARC = "WARC/1.0\r\n" +
+	"WARC-Type: warcinfo\r\n" +
+	"Content-Length: 4\r\n" +
+	"\r\n" +
+	"info\r\n\r\n" +
+	"WARC/1.0\r\n" +
+	"WARC-Type: response\r\n" +
+	"WARC-Target-URI: <https://example.com/readme.txt>\r\n" +
+	"Content-Type: application/http; msgtype=response\r\n" +
+	"Content-Length: " + strconv.Itoa(len(testHTTPResponse)) + "\r\n" +
+	"\r\n" +
+	testHTTPResponse + "\r\n\r\n"
+
+func TestCapture(t *testing.T) {
+	dir, err := ioutil.TempDir("", "identify")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, "capture.har"), []byte(testHAR), 0600)
+	ioutil.WriteFile(filepath.Join(dir, "capture.warc"), []byte(testWARC), 0600)
+
+	writeFiles(t, filepath.Join(dir, "mirror"), map[string]string{
+		"example.com/readme.txt":    "1.1.0",
+		"example.com/css/style.css": "body {}",
+		"responses.har":             testHAR,
+	})
+
+	tests := map[string]map[string]string{
+		"capture.har": {
+			"https://example.com/readme.txt":          "1.1.0",
+			"https://example.com/css/style.css?ver=1": "body {}",
+			"https://example.com/logo.png":            "",
+			"https://example.com/missing.js":          "",
+		},
+		"capture.warc": {
+			"https://example.com/readme.txt": "1.1.0",
+			"https://example.com/missing.js": "",
+		},
+		"mirror": {
+			"https://example.com/readme.txt":          "1.1.0",
+			"https://example.com/css/style.css":       "body {}",
+			"https://example.com/css/style.css?ver=1": "body {}",
+			"https://example.com/../../etc":           "",
+		},
+	}
+
+	for name, urls := range tests {
+		transport, err := openCapture(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		client := &http.Client{Transport: transport}
+
+		for u, expected := range urls {
+			resp, err := client.Get(u)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			if expected == "" && resp.StatusCode != http.StatusNotFound {
+				t.Errorf("%s: expected %s to be not found, got %d", name, u, resp.StatusCode)
+			} else if expected != "" && (resp.StatusCode != http.StatusOK || string(data) != expected) {
+				t.Errorf("%s: unexpected response for %s: %d %q", name, u, resp.StatusCode, string(data))
+			}
+		}
+	}
+}
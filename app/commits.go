@@ -0,0 +1,231 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/fatih/color"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+const defaultMaxCommits = 1000
+
+// CommitMatch is a commit of which the tree matches all fetched files.
+type CommitMatch struct {
+	Hash string
+	Date time.Time
+
+	// PreviousTag and NextTag are the nearest tags by date, before and
+	// after the commit
+	PreviousTag string
+	NextTag     string
+}
+
+type taggedCommit struct {
+	name string
+	date time.Time
+}
+
+// nearestTags returns the names of the last tag before and the first tag after
+// date, tags should be sorted by date.
+func nearestTags(tags []taggedCommit, date time.Time) (string, string) {
+	previous, next := "", ""
+
+	for _, tag := range tags {
+		if !tag.date.After(date) {
+			previous = tag.name
+		} else if next == "" {
+			next = tag.name
+		}
+	}
+
+	return previous, next
+}
+
+// FindCommits walks the history back from start, without passing the
+// commits of stop, and returns the commits for which match returns true. At
+// most max commits will be visited.
+func (s *gitSource) FindCommits(start []Reference, stop []Reference, tags []Reference, max int, match func(Reference) (bool, error)) ([]*CommitMatch, error) {
+	tagged := []taggedCommit{}
+	for _, ref := range tags {
+		if gr, ok := ref.(*gitReference); !ok || gr.commit == nil {
+		} else {
+			tagged = append(tagged, taggedCommit{
+				name: gr.name,
+				date: gr.commit.Committer.When,
+			})
+		}
+	}
+
+	sort.Slice(tagged, func(i, j int) bool {
+		return tagged[i].date.Before(tagged[j].date)
+	})
+
+	queue := []*object.Commit{}
+	for _, ref := range start {
+		if gr, ok := ref.(*gitReference); !ok || gr.commit == nil {
+		} else {
+			queue = append(queue, gr.commit)
+		}
+	}
+
+	seen := map[plumbing.Hash]bool{}
+	for _, ref := range stop {
+		if gr, ok := ref.(*gitReference); !ok || gr.commit == nil {
+		} else {
+			seen[gr.commit.Hash] = true
+		}
+	}
+
+	matches := []*CommitMatch{}
+
+	for visited := 0; len(queue) > 0 && visited < max; {
+		commit := queue[0]
+		queue = queue[1:]
+
+		if seen[commit.Hash] {
+			continue
+		}
+
+		seen[commit.Hash] = true
+
+		visited++
+
+		tree, err := commit.Tree()
+		if err != nil {
+			return nil, err
+		}
+
+		if ok, err := match(&gitReference{
+			name:   commit.Hash.String(),
+			commit: commit,
+			tree:   tree,
+		}); err != nil {
+			return nil, err
+		} else if ok {
+			previous, next := nearestTags(tagged, commit.Committer.When)
+
+			matches = append(matches, &CommitMatch{
+				Hash:        commit.Hash.String(),
+				Date:        commit.Committer.When,
+				PreviousTag: previous,
+				NextTag:     next,
+			})
+		}
+
+		err = commit.Parents().ForEach(func(parent *object.Commit) error {
+			queue = append(queue, parent)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Date.Before(matches[j].Date)
+	})
+
+	return matches, nil
+}
+
+func commitDate(ref Reference) (time.Time, bool) {
+	if gr, ok := ref.(*gitReference); !ok || gr.commit == nil {
+		return time.Time{}, false
+	} else {
+		return gr.commit.Committer.When, true
+	}
+}
+
+func printCommit(w io.Writer, match *CommitMatch) {
+	fmt.Fprintln(w, color.GreenString(" |  %s %s (after: %s, before: %s)", match.Hash, match.Date.Format(time.RFC3339), match.PreviousTag, match.NextTag))
+}
+
+// identifyCommits searches the history between the tags before and after the
+// best matching versions for commits that match all fetched files. The walk
+// starts at the tag after the best matching versions, or at the best matching
+// versions when there isn't a later tag, and stops at the tag before them.
+func (b *identify) identifyCommits(s *gitSource, branches []Reference, tags []Reference, best []string) error {
+	fmt.Fprintln(b.out, color.YellowString("[+] Searching commits matching all remote files"))
+
+	byName := map[string]Reference{}
+	for _, ref := range append(branches, tags...) {
+		byName[ref.Name()] = ref
+	}
+
+	bestRefs := []Reference{}
+
+	var first, last time.Time
+	for _, name := range best {
+		ref, ok := byName[name]
+		if !ok {
+			continue
+		}
+
+		bestRefs = append(bestRefs, ref)
+
+		if date, ok := commitDate(ref); !ok {
+		} else {
+			if first.IsZero() || date.Before(first) {
+				first = date
+			}
+
+			if last.IsZero() || date.After(last) {
+				last = date
+			}
+		}
+	}
+
+	// the nearest tags before and after the best matching versions
+	var previous, next Reference
+	var previousDate, nextDate time.Time
+	for _, ref := range tags {
+		if date, ok := commitDate(ref); !ok {
+		} else if date.Before(first) && (previous == nil || date.After(previousDate)) {
+			previous, previousDate = ref, date
+		} else if date.After(last) && (next == nil || date.Before(nextDate)) {
+			next, nextDate = ref, date
+		}
+	}
+
+	start := bestRefs
+	if next != nil {
+		start = []Reference{next}
+	}
+
+	stop := []Reference{}
+	if previous != nil {
+		stop = append(stop, previous)
+	}
+
+	matches, err := s.FindCommits(start, stop, tags, b.maxCommits, b.matchesAll)
+	if err != nil {
+		return err
+	}
+
+	b.commitMatches = matches
+
+	if len(matches) == 0 {
+		fmt.Fprintln(b.out, color.RedString("[!] Could not find commits matching all remote files"))
+		return nil
+	}
+
+	fmt.Fprintln(b.out, color.GreenString("[+] Web application has been identified as one of the following commits: "))
+
+	if len(matches) <= 10 {
+		for _, match := range matches {
+			printCommit(b.out, match)
+		}
+	} else {
+		printCommit(b.out, matches[0])
+		fmt.Fprintln(b.out, color.GreenString(" |  ... %d commits", len(matches)-2))
+		printCommit(b.out, matches[len(matches)-1])
+	}
+
+	fmt.Fprintf(b.out, "\n")
+	return nil
+}
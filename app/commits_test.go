@@ -0,0 +1,157 @@
+package app
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+func TestNearestTags(t *testing.T) {
+	now := time.Now()
+
+	tags := []taggedCommit{
+		{name: "1.0.0", date: now.Add(-48 * time.Hour)},
+		{name: "1.0.1", date: now.Add(-24 * time.Hour)},
+		{name: "1.1.0", date: now.Add(24 * time.Hour)},
+	}
+
+	if previous, next := nearestTags(tags, now); previous != "1.0.1" || next != "1.1.0" {
+		t.Errorf("Expected 1.0.1 and 1.1.0, got %s and %s", previous, next)
+	}
+
+	if previous, next := nearestTags(tags, now.Add(-72*time.Hour)); previous != "" || next != "1.0.0" {
+		t.Errorf("Expected no previous tag and 1.0.0, got %s and %s", previous, next)
+	}
+}
+
+// testCommit stores a commit with a single readme.txt and returns its hash.
+func testCommit(t *testing.T, s *memory.Storage, readme string, date time.Time, parent *plumbing.Hash) plumbing.Hash {
+	store := func(typ plumbing.ObjectType, data []byte) plumbing.Hash {
+		obj := s.NewEncodedObject()
+		obj.SetType(typ)
+		obj.SetSize(int64(len(data)))
+
+		w, err := obj.Writer()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w.Write(data)
+		w.Close()
+
+		h, err := s.SetEncodedObject(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return h
+	}
+
+	blob := store(plumbing.BlobObject, []byte(readme))
+
+	tree := &object.Tree{
+		Entries: []object.TreeEntry{
+			{Name: "readme.txt", Mode: filemode.Regular, Hash: blob},
+		},
+	}
+
+	obj := &plumbing.MemoryObject{}
+	if err := tree.Encode(obj); err != nil {
+		t.Fatal(err)
+	}
+
+	r, _ := obj.Reader()
+	data, _ := ioutil.ReadAll(r)
+
+	treeHash := store(plumbing.TreeObject, data)
+
+	signature := fmt.Sprintf("identify <identify@example.com> %d +0000", date.Unix())
+
+	commit := fmt.Sprintf("tree %s\n", treeHash)
+	if parent != nil {
+		commit += fmt.Sprintf("parent %s\n", *parent)
+	}
+
+	commit += fmt.Sprintf("author %s\ncommitter %s\n\n%s\n", signature, signature, readme)
+
+	return store(plumbing.CommitObject, []byte(commit))
+}
+
+func TestFindCommits(t *testing.T) {
+	s := memory.NewStorage()
+
+	r, err := git.Init(s, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	date := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// 1.0.0, an untagged snapshot, 1.1.0 and the development after it
+	commits := []string{"1.0.0", "snapshot", "1.1.0", "dev 1", "dev 2", "dev 3", "dev 4", "dev 5"}
+	hashes := map[string]plumbing.Hash{}
+
+	var parent *plumbing.Hash
+	for i, readme := range commits {
+		h := testCommit(t, s, readme, date.Add(time.Duration(i)*24*time.Hour), parent)
+		hashes[readme] = h
+		parent = &h
+	}
+
+	for name, ref := range map[plumbing.ReferenceName]plumbing.Hash{
+		"refs/tags/1.0.0":   hashes["1.0.0"],
+		"refs/tags/1.1.0":   hashes["1.1.0"],
+		"refs/heads/master": hashes["dev 5"],
+	} {
+		if err := s.SetReference(plumbing.NewHashReference(name, ref)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	source := &gitSource{r: r, out: ioutil.Discard}
+
+	branches, err := source.Branches()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tags, err := source.Tags()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, _ := CalcHash(ioutil.NopCloser(strings.NewReader("snapshot")))
+
+	b := &identify{
+		hashes: map[string]*Result{
+			"readme.txt": {Hash: hash},
+		},
+		application: &Application{},
+		out:         ioutil.Discard,
+	}
+
+	// the budget would be spent on the development commits when starting
+	// from the branches
+	b.maxCommits = 2
+
+	if err := b.identifyCommits(source, branches, tags, []string{"1.0.0"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(b.commitMatches) != 1 {
+		t.Fatalf("Expected 1 matching commit, got %d", len(b.commitMatches))
+	}
+
+	match := b.commitMatches[0]
+	if match.Hash != hashes["snapshot"].String() || match.PreviousTag != "1.0.0" || match.NextTag != "1.1.0" {
+		t.Errorf("Unexpected match: %#v", match)
+	}
+}
@@ -0,0 +1,210 @@
+package app
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Component is a plugin, extension or theme of an application.
+type Component struct {
+	// Kind is the name of the component rule, like plugin or theme
+	Kind string `json:"kind"`
+	Slug string `json:"slug"`
+}
+
+// validSlug matches the slugs that can safely be used in urls and paths.
+var validSlug = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// parseSlug splits a slug scoped with the kind of component, like
+// plugin:akismet, the kind is empty when it isn't scoped.
+func parseSlug(s string) (string, string, error) {
+	kind, slug := "", s
+	if i := strings.Index(s, ":"); i == 0 {
+		return "", "", fmt.Errorf("Invalid kind of slug: %s", s)
+	} else if i > 0 {
+		kind, slug = s[:i], s[i+1:]
+	}
+
+	if !validSlug.MatchString(slug) {
+		return "", "", fmt.Errorf("Invalid slug: %s", s)
+	}
+
+	return kind, slug, nil
+}
+
+func replaceSlug(s string, slug string) string {
+	return strings.Replace(s, "{slug}", slug, -1)
+}
+
+// withSlug returns the component rule for a single slug.
+func (a Application) withSlug(slug string) Application {
+	c := a
+
+	c.Name = replaceSlug(a.Name, slug)
+	c.Path = replaceSlug(a.Path, slug)
+	c.Root = replaceSlug(a.Root, slug)
+	c.Repository = replaceSlug(a.Repository, slug)
+
+	c.Files = make([]string, len(a.Files))
+	for i, file := range a.Files {
+		c.Files[i] = replaceSlug(file, slug)
+	}
+
+	c.CPE.Vendor = replaceSlug(a.CPE.Vendor, slug)
+	c.CPE.Product = replaceSlug(a.CPE.Product, slug)
+	c.Purl.Namespace = replaceSlug(a.Purl.Namespace, slug)
+	c.Purl.Name = replaceSlug(a.Purl.Name, slug)
+
+	if a.Archives != nil {
+		c.Archives = map[string]string{}
+		for version, location := range a.Archives {
+			c.Archives[version] = replaceSlug(location, slug)
+		}
+	}
+
+	return c
+}
+
+// componentTarget is a component of the application, with its rule for the
+// slug and the url it is installed at.
+type componentTarget struct {
+	Component
+
+	application Application
+	targetURL   *url.URL
+}
+
+// kindSlugs returns the slugs set with Components per kind of component of
+// the application. Slugs that aren't scoped with their kind are only allowed
+// when the application has a single kind of components.
+func (b *identify) kindSlugs(kinds []string) (map[string][]string, error) {
+	slugs := map[string][]string{}
+
+	for _, s := range b.slugs {
+		kind, slug, err := parseSlug(s)
+		if err != nil {
+			return nil, err
+		}
+
+		if kind != "" {
+		} else if len(kinds) == 1 {
+			kind = kinds[0]
+		} else {
+			return nil, fmt.Errorf("Slug %s should be scoped with its kind, one of: %s", s, strings.Join(kinds, ", "))
+		}
+
+		if _, ok := b.application.Components[kind]; !ok {
+			return nil, fmt.Errorf("Unknown kind of component: %s", kind)
+		}
+
+		slugs[kind] = append(slugs[kind], slug)
+	}
+
+	return slugs, nil
+}
+
+// componentTargets returns the components of the application that will be
+// identified, ordered by kind. When slugs have been set with Components,
+// only those are identified, instead of the slugs of the rules.
+func (b *identify) componentTargets() ([]*componentTarget, error) {
+	kinds := []string{}
+	for kind := range b.application.Components {
+		kinds = append(kinds, kind)
+	}
+
+	sort.Strings(kinds)
+
+	kindSlugs, err := b.kindSlugs(kinds)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := []*componentTarget{}
+
+	for _, kind := range kinds {
+		rule := b.application.Components[kind]
+
+		slugs := rule.Slugs
+		if len(b.slugs) > 0 {
+			slugs = kindSlugs[kind]
+		}
+
+		for _, slug := range slugs {
+			if !validSlug.MatchString(slug) {
+				return nil, fmt.Errorf("Invalid slug: %s", slug)
+			}
+
+			application := rule.withSlug(slug)
+
+			rel, err := url.Parse(application.Path)
+			if err != nil {
+				return nil, err
+			}
+
+			targets = append(targets, &componentTarget{
+				Component: Component{
+					Kind: kind,
+					Slug: slug,
+				},
+				application: application,
+				targetURL:   b.targetURL.ResolveReference(rel),
+			})
+		}
+	}
+
+	return targets, nil
+}
+
+// identifyComponents identifies the version of every component of the
+// application, components that aren't installed are skipped.
+func (b *identify) identifyComponents() error {
+	targets, err := b.componentTargets()
+	if err != nil {
+		return err
+	}
+
+	for _, target := range targets {
+		fmt.Fprintln(b.out)
+		fmt.Fprintln(b.out, color.YellowString("[+] Identifying %s %s", target.Kind, target.Slug))
+
+		component := target.Component
+
+		c := &identify{
+			config:      b.config,
+			client:      b.client,
+			transport:   b.transport,
+			hashes:      map[string]*Result{},
+			versions:    []string{},
+			application: &target.application,
+			db:          b.db,
+			cachePath:   b.cachePath,
+			proxyURL:    b.proxyURL,
+			rootfs:      b.rootfs,
+			dbHash:      b.dbHash,
+			addresses:   b.addresses,
+			index:       b.index,
+			metrics:     b.metrics,
+			ctx:         b.ctx,
+			out:         b.out,
+			component:   &component,
+		}
+
+		c.targetURL = target.targetURL
+
+		if err := c.identify(); err != nil {
+			fmt.Fprintln(b.out, color.RedString("[!] Error identifying %s %s: %s", target.Kind, target.Slug, err.Error()))
+			continue
+		} else if len(c.hashes) == 0 {
+			continue
+		}
+
+		b.components = append(b.components, c)
+	}
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+type cdxBOM struct {
+	BOMFormat       string              `json:"bomFormat"`
+	SpecVersion     string              `json:"specVersion"`
+	SerialNumber    string              `json:"serialNumber"`
+	Version         int                 `json:"version"`
+	Metadata        cdxMetadata         `json:"metadata"`
+	Components      []*cdxComponent     `json:"components"`
+	Vulnerabilities []*cdxVulnerability `json:"vulnerabilities,omitempty"`
+}
+
+type cdxMetadata struct {
+	Timestamp string        `json:"timestamp"`
+	Tools     []cdxTool     `json:"tools"`
+	Component *cdxComponent `json:"component"`
+}
+
+type cdxTool struct {
+	Vendor  string `json:"vendor"`
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxComponent struct {
+	BOMRef     string          `json:"bom-ref,omitempty"`
+	Type       string          `json:"type"`
+	Name       string          `json:"name"`
+	Version    string          `json:"version,omitempty"`
+	CPE        string          `json:"cpe,omitempty"`
+	Purl       string          `json:"purl,omitempty"`
+	Properties []cdxProperty   `json:"properties,omitempty"`
+	Components []*cdxComponent `json:"components,omitempty"`
+}
+
+type cdxVulnerability struct {
+	BOMRef      string      `json:"bom-ref,omitempty"`
+	ID          string      `json:"id"`
+	Description string      `json:"description,omitempty"`
+	Ratings     []cdxRating `json:"ratings,omitempty"`
+	Advisories  []cdxLink   `json:"advisories,omitempty"`
+	Affects     []cdxAffect `json:"affects"`
+}
+
+type cdxRating struct {
+	Severity string `json:"severity"`
+}
+
+type cdxLink struct {
+	URL string `json:"url"`
+}
+
+type cdxAffect struct {
+	Ref string `json:"ref"`
+}
+
+func serialNumber() string {
+	data := make([]byte, 16)
+	rand.Read(data)
+
+	// version 4 uuid
+	data[6] = (data[6] & 0x0f) | 0x40
+	data[8] = (data[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", data[0:4], data[4:6], data[6:8], data[8:10], data[10:])
+}
+
+// cdxSeverity maps the severity of an advisory to the severities of
+// CycloneDX.
+func cdxSeverity(s string) string {
+	switch s = strings.ToLower(s); s {
+	case "critical", "high", "medium", "low", "info", "none":
+		return s
+	case "moderate":
+		return "medium"
+	default:
+		return "unknown"
+	}
+}
+
+// cdxComponents converts the report and its components, vulnerabilities of
+// the best matching version are added to vulns.
+func (r *Report) cdxComponents(vulns *[]*cdxVulnerability) []*cdxComponent {
+	c := &cdxComponent{
+		BOMRef: r.Target,
+		Type:   "application",
+		Name:   r.Application,
+	}
+
+	if r.Component != nil {
+		c.Type = "library"
+		c.Properties = append(c.Properties, cdxProperty{Name: "identify:component", Value: r.Component.Kind})
+	}
+
+	if best := r.Best(); best != nil {
+		c.Version = best.Version
+		c.CPE = best.CPE
+		c.Purl = best.Purl
+
+		c.Properties = append(c.Properties, cdxProperty{Name: "identify:score", Value: fmt.Sprintf("%.2f", best.Score)})
+
+		for _, vr := range r.Versions[1:] {
+			if vr.Matches != best.Matches {
+				break
+			}
+
+			c.Properties = append(c.Properties, cdxProperty{Name: "identify:candidate", Value: vr.Version})
+		}
+
+		for _, advisory := range best.Advisories {
+			v := &cdxVulnerability{
+				BOMRef:      r.Target + "#" + advisory.ID,
+				ID:          advisory.ID,
+				Description: advisory.Summary,
+				Affects:     []cdxAffect{{Ref: c.BOMRef}},
+			}
+
+			if advisory.Severity != "" {
+				v.Ratings = []cdxRating{{Severity: cdxSeverity(advisory.Severity)}}
+			}
+
+			for _, ref := range advisory.References {
+				v.Advisories = append(v.Advisories, cdxLink{URL: ref})
+			}
+
+			*vulns = append(*vulns, v)
+		}
+	}
+
+	for _, component := range r.Components {
+		c.Components = append(c.Components, component.cdxComponents(vulns)...)
+	}
+
+	return []*cdxComponent{c}
+}
+
+// cdxHost returns the name of the scanned host of the target: the host of a
+// url, or the base name of a local path or image, which have no host.
+func cdxHost(target string) string {
+	if u, err := url.Parse(target); err != nil {
+	} else if u.Host != "" {
+		return u.Host
+	} else if u.Scheme == "file" {
+		return path.Base(u.Path)
+	}
+
+	// images are named image:/path
+	return path.Base(strings.SplitN(target, ":/", 2)[0])
+}
+
+// WriteCycloneDX writes the report as CycloneDX json to w, with the
+// identified application and components as components of the scanned host.
+func (r *Report) WriteCycloneDX(w io.Writer) error {
+	host := cdxHost(r.Target)
+
+	vulns := []*cdxVulnerability{}
+
+	bom := &cdxBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.4",
+		SerialNumber: serialNumber(),
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Tools: []cdxTool{
+				{Vendor: "DutchSec", Name: "identify"},
+			},
+			Component: &cdxComponent{
+				BOMRef: host,
+				Type:   "device",
+				Name:   host,
+			},
+		},
+	}
+
+	if r.Provenance != nil {
+		bom.Metadata.Tools[0].Version = r.Provenance.ToolVersion
+	}
+
+	bom.Components = r.cdxComponents(&vulns)
+	bom.Vulnerabilities = vulns
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(bom)
+}
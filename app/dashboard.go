@@ -0,0 +1,229 @@
+package app
+
+import (
+	"io"
+	"net/http"
+)
+
+// dashboard is the web interface of the server, self-contained so it works
+// without access to the internet.
+const dashboard = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Identify</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #222; margin: 0; }
+header { background: #222; color: #fff; padding: .8em 2em; }
+header h1 { margin: 0; font-size: 1.3em; }
+main { display: grid; grid-template-columns: 1fr 1fr; gap: 2em; padding: 1em 2em; }
+section { min-width: 0; }
+h2 { border-bottom: 1px solid #ccc; padding-bottom: .2em; font-size: 1.1em; }
+table { border-collapse: collapse; width: 100%; font-size: 90%; }
+th, td { border: 1px solid #ddd; padding: .3em .5em; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; }
+tr.target { cursor: pointer; }
+tr.target:hover, tr.selected { background: #eef; }
+code { font-family: Menlo, Consolas, monospace; font-size: 90%; word-break: break-all; }
+input, button { font-size: 1em; padding: .3em .5em; }
+input[name=url] { width: 50%; }
+.bar { background: #4caf50; height: 1em; }
+.failed, .warning { color: #b71c1c; }
+.muted { color: #777; }
+pre { background: #f4f4f4; padding: 1em; overflow: auto; max-height: 20em; }
+</style>
+</head>
+<body>
+<header><h1>Identify</h1></header>
+<main>
+<section>
+<h2>Submit scan</h2>
+<form id="submit">
+<input name="url" placeholder="https://example.com/" required>
+<input name="application" placeholder="auto">
+<button type="submit">Scan</button>
+<span id="message" class="warning"></span>
+</form>
+<h2>Targets</h2>
+<table id="targets"></table>
+<h2>Version distribution</h2>
+<table id="distribution"></table>
+</section>
+<section>
+<h2>History</h2>
+<table id="history"><tr><td class="muted">Select a target</td></tr></table>
+<h2>Evidence</h2>
+<div id="evidence"><p class="muted">Select a scan</p></div>
+</section>
+</main>
+<script>
+var jobs = [], selected = null;
+
+// the token of a server requiring one is passed as #token=...
+var token = new URLSearchParams(location.hash.slice(1)).get("token");
+
+function api(path, init) {
+	init = init || {};
+	init.headers = init.headers || {};
+	if (token) init.headers["Authorization"] = "Bearer " + token;
+	return fetch(path, init);
+}
+
+function esc(s) {
+	return String(s == null ? "" : s).replace(/[&<>"']/g, function(c) {
+		return {"&": "&amp;", "<": "&lt;", ">": "&gt;", '"': "&quot;", "'": "&#39;"}[c];
+	});
+}
+
+function date(s) {
+	return s && s.indexOf("0001-") != 0 ? new Date(s).toLocaleString() : "";
+}
+
+function status(job) {
+	if (job.status == "failed") return '<span class="failed">failed</span> ' + esc(job.error);
+	if (job.status != "finished") return esc(job.status);
+	return job.version ? esc(job.version) + ' <span class="muted">' + Math.round(job.score * 100) + '%</span>' : '<span class="muted">unknown</span>';
+}
+
+function row(cells, attrs) {
+	return "<tr" + (attrs || "") + ">" + cells.map(function(c) { return "<td>" + c + "</td>"; }).join("") + "</tr>";
+}
+
+function renderTargets() {
+	var latest = {};
+	jobs.forEach(function(job) {
+		if (!latest[job.url]) latest[job.url] = job;
+	});
+
+	var html = "<tr><th>Target</th><th>Application</th><th>Version</th><th>Scanned</th></tr>";
+	Object.keys(latest).sort().forEach(function(url) {
+		var job = latest[url];
+		html += row([esc(url), esc(job.application), status(job), date(job.created)], ' class="target' + (url == selected ? ' selected' : '') + '" data-url="' + esc(url) + '"');
+	});
+
+	document.getElementById("targets").innerHTML = html;
+
+	var counts = {}, total = 0;
+	Object.keys(latest).forEach(function(url) {
+		var job = latest[url];
+		if (job.status != "finished" || !job.version) return;
+
+		var key = job.application + " " + job.version;
+		counts[key] = (counts[key] || 0) + 1;
+		total++;
+	});
+
+	html = "<tr><th>Version</th><th>Hosts</th><th></th></tr>";
+	Object.keys(counts).sort().forEach(function(key) {
+		html += row([esc(key), counts[key], '<div class="bar" style="width: ' + Math.round(counts[key] * 100 / total) + '%"></div>']);
+	});
+
+	document.getElementById("distribution").innerHTML = html;
+}
+
+function renderHistory() {
+	if (!selected) return;
+
+	var html = "<tr><th>Created</th><th>Application</th><th>Version</th><th></th></tr>";
+	jobs.filter(function(job) { return job.url == selected; }).forEach(function(job) {
+		html += row([date(job.created), esc(job.application), status(job), '<a href="#" data-id="' + esc(job.id) + '">evidence</a>']);
+	});
+
+	document.getElementById("history").innerHTML = html;
+}
+
+function showEvidence(id) {
+	var el = document.getElementById("evidence");
+
+	api("api/scans/" + id + "/report").then(function(resp) {
+		if (resp.ok) return resp.json();
+		return api("api/scans/" + id + "/log").then(function(resp) { return resp.text(); }).then(function(log) {
+			el.innerHTML = "<pre>" + esc(log) + "</pre>";
+		});
+	}).then(function(report) {
+		if (!report) return;
+
+		var html = "<p><b>" + esc(report.application) + "</b> on <code>" + esc(report.target) + "</code>";
+		if (report.latest) html += ", latest release " + esc(report.latest);
+		html += "</p><table><tr><th>Version</th><th>Score</th><th>Files</th><th>Advisories</th></tr>";
+		(report.versions || []).forEach(function(v) {
+			html += row([esc(v.version), Math.round(v.score * 100) + "%", v.matches, (v.advisories || []).map(function(a) { return '<span class="warning">' + esc(a.id) + "</span>"; }).join(" ")]);
+		});
+
+		html += "</table><p></p><table><tr><th>File</th><th>Status</th><th>SHA-1</th><th>Matching versions</th></tr>";
+		(report.files || []).forEach(function(f) {
+			html += row([esc(f.path), f.status_code, "<code>" + esc(f.hash) + "</code>", f.versions && f.versions.length ? esc(f.versions.join(", ")) : f.skipped ? '<span class="muted">' + esc(f.skipped) + "</span>" : '<span class="warning">no known version</span>']);
+		});
+
+		html += "</table>";
+		(report.components || []).forEach(function(c) {
+			var best = (c.versions || [])[0];
+			html += "<p>" + esc(c.component.kind) + " <b>" + esc(c.application) + "</b> " + (best ? esc(best.version) : '<span class="muted">unknown</span>') + "</p>";
+		});
+
+		el.innerHTML = html;
+	});
+}
+
+function refresh() {
+	api("api/scans").then(function(resp) { return resp.json(); }).then(function(data) {
+		jobs = data;
+		renderTargets();
+		renderHistory();
+
+		var pending = jobs.some(function(job) { return job.status == "queued" || job.status == "running"; });
+		setTimeout(refresh, pending ? 2000 : 10000);
+	});
+}
+
+document.getElementById("targets").addEventListener("click", function(e) {
+	var tr = e.target.closest("tr[data-url]");
+	if (!tr) return;
+
+	selected = tr.getAttribute("data-url");
+	renderTargets();
+	renderHistory();
+});
+
+document.getElementById("history").addEventListener("click", function(e) {
+	var a = e.target.closest("a[data-id]");
+	if (!a) return;
+
+	e.preventDefault();
+	showEvidence(a.getAttribute("data-id"));
+});
+
+document.getElementById("submit").addEventListener("submit", function(e) {
+	e.preventDefault();
+
+	var form = e.target;
+	api("api/scans", {
+		method: "POST",
+		headers: {"Content-Type": "application/json"},
+		body: JSON.stringify({url: form.url.value, application: form.application.value})
+	}).then(function(resp) { return resp.json(); }).then(function(job) {
+		document.getElementById("message").textContent = job.error || "";
+		if (job.error) return;
+
+		selected = job.url;
+		form.url.value = "";
+		refresh();
+	});
+});
+
+refresh();
+</script>
+</body>
+</html>
+`
+
+// handleDashboard serves the dashboard.
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		writeError(w, http.StatusNotFound, "Not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.WriteString(w, dashboard)
+}
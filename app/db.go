@@ -8,6 +8,28 @@ type Application struct {
 
 	Root       string `yaml:"root"`
 	Repository string `yaml:"repository"`
+
+	// Type is the type of source, git (default), hg, svn or archive
+	Type string `yaml:"type"`
+
+	// Archives contains the location of the release archive per version,
+	// for sources of type archive. When empty, repository is the directory
+	// containing the archives.
+	Archives map[string]string `yaml:"archives"`
+
+	// Path is the path of a component relative to the application, like
+	// wp-content/plugins/{slug}/
+	Path string `yaml:"path"`
+
+	// Slugs are the components to enumerate by default, {slug} in path,
+	// root, repository, files and archives is replaced by the slug
+	Slugs []string `yaml:"slugs"`
+
+	// Components are the plugins, extensions and themes of the application
+	Components map[string]Application `yaml:"components"`
+
+	CPE  CPE  `yaml:"cpe"`
+	Purl Purl `yaml:"purl"`
 }
 
 type DB struct {
@@ -0,0 +1,37 @@
+package app
+
+import (
+	"io/ioutil"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestDB(t *testing.T) {
+	data, err := ioutil.ReadFile("../db.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var db DB
+	if err := yaml.Unmarshal(data, &db.Application); err != nil {
+		t.Fatal(err)
+	}
+
+	wordpress, ok := db.Application["wordpress"]
+	if !ok {
+		t.Fatal("Expected wordpress in rule set")
+	}
+
+	plugin, ok := wordpress.Components["plugin"]
+	if !ok {
+		t.Fatal("Expected plugin component for wordpress")
+	}
+
+	c := plugin.withSlug("akismet")
+	if c.Path != "wp-content/plugins/akismet/" {
+		t.Errorf("Unexpected path: %s", c.Path)
+	} else if c.Repository != "https://plugins.svn.wordpress.org/akismet" {
+		t.Errorf("Unexpected repository: %s", c.Repository)
+	}
+}
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+
+	"github.com/fatih/color"
+)
+
+// ApplicationAuto detects the application by the files the target serves.
+const ApplicationAuto = "auto"
+
+// detectProbes is the number of files probed for each application.
+const detectProbes = 3
+
+// probe returns true if the target serves file, ignoring soft-404 pages.
+func (b *identify) probe(fingerprints map[string]*softNotFound, file string) (bool, error) {
+	rel, err := url.Parse(file)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := b.get(b.targetURL.ResolveReference(rel).String())
+	if err != nil {
+		return false, err
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		closeBody(resp)
+		return false, nil
+	}
+
+	body, err := readBody(resp, b.maxBodySize)
+	if err != nil {
+		return false, err
+	}
+
+	if _, ok := isSoftNotFound(fingerprints, file, resp, body); ok {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// detectApplication returns the application of the rule set of which the
+// target serves the most files, probing the first files of each application.
+func (b *identify) detectApplication() (string, error) {
+	names := []string{}
+	for name := range b.db.Application {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	defer func() {
+		b.application = nil
+	}()
+
+	best, bestFound := "", 0
+
+	for _, name := range names {
+		application := b.db.Application[name]
+		if len(application.Files) > detectProbes {
+			application.Files = application.Files[:detectProbes]
+		}
+
+		b.application = &application
+
+		fingerprints := map[string]*softNotFound{}
+
+		if b.noSoft404 {
+		} else if v, err := b.fingerprintNotFound(); err != nil {
+			return "", err
+		} else {
+			fingerprints = v
+		}
+
+		found := 0
+
+		for _, file := range application.Files {
+			if ok, err := b.probe(fingerprints, file); err != nil {
+				return "", err
+			} else if ok {
+				found++
+			}
+		}
+
+		if b.debug {
+			fmt.Fprintf(b.out, "[ ] Probed %s: %d of %d files found\n", name, found, len(application.Files))
+		}
+
+		if found > bestFound {
+			best, bestFound = name, found
+		}
+	}
+
+	if best == "" {
+		return "", fmt.Errorf("Could not detect application")
+	}
+
+	fmt.Fprintln(b.out, color.YellowString("[+] Detected application %s", best))
+	return best, nil
+}
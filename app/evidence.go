@@ -0,0 +1,392 @@
+package app
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// evidenceTimeFormat names the directory of a scan, with nanoseconds so
+// scans of the same target in the same second don't collide.
+const evidenceTimeFormat = "20060102T150405.000000000Z"
+
+// recordedResponse is a response as returned by the target, of which the
+// http message is stored in the spool of the recorder.
+type recordedResponse struct {
+	URL        string
+	StatusCode int
+	Header     http.Header
+	Time       time.Time
+
+	// Size, SHA1 and SHA256 are of the body
+	Size   int64
+	SHA1   string
+	SHA256 string
+
+	// offset and headerSize locate the http message in the spool
+	offset     int64
+	headerSize int64
+}
+
+// recorder records the responses of the transport, reading bodies up to one
+// byte more than the maximum size, so they are still rejected when too large.
+// Responses are written as WARC records to a spool file as they arrive, only
+// their metadata is kept in memory.
+type recorder struct {
+	http.RoundTripper
+
+	maxBodySize int64
+
+	m         sync.Mutex
+	spool     *os.File
+	size      int64
+	responses []*recordedResponse
+}
+
+// newRecorder returns a recorder of the responses of transport, spooling
+// them to a temporary file that is removed by Close.
+func newRecorder(transport http.RoundTripper, maxBodySize int64) (*recorder, error) {
+	spool, err := ioutil.TempFile("", "identify-responses")
+	if err != nil {
+		return nil, err
+	}
+
+	return &recorder{
+		RoundTripper: transport,
+		maxBodySize:  maxBodySize,
+		spool:        spool,
+	}, nil
+}
+
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.RoundTripper.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	var body io.Reader = resp.Body
+	if r.maxBodySize > 0 {
+		body = io.LimitReader(resp.Body, r.maxBodySize+1)
+	}
+
+	data, err := ioutil.ReadAll(body)
+	resp.Body.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+	header := http.Header{}
+	for k, v := range resp.Header {
+		header[k] = v
+	}
+
+	response := &recordedResponse{
+		URL:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     header,
+		Time:       time.Now().UTC(),
+		Size:       int64(len(data)),
+		SHA1:       fmt.Sprintf("%x", sha1.Sum(data)),
+		SHA256:     fmt.Sprintf("%x", sha256.Sum256(data)),
+	}
+
+	if err := r.write(response, data); err != nil {
+		return nil, fmt.Errorf("Could not record response: %s", err.Error())
+	}
+
+	return resp, nil
+}
+
+// write appends the response with body as WARC response record to the
+// spool.
+func (r *recorder) write(response *recordedResponse, body []byte) error {
+	header := response.httpHeader()
+
+	record := fmt.Sprintf("WARC/1.0\r\n"+
+		"WARC-Type: response\r\n"+
+		"WARC-Record-ID: <%s>\r\n"+
+		"WARC-Date: %s\r\n"+
+		"WARC-Target-URI: %s\r\n"+
+		"Content-Type: application/http; msgtype=response\r\n"+
+		"Content-Length: %d\r\n\r\n", serialNumber(), response.Time.Format(time.RFC3339), response.URL, len(header)+len(body))
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	response.offset = r.size + int64(len(record))
+	response.headerSize = int64(len(header))
+
+	for _, data := range [][]byte{[]byte(record), header, body, []byte("\r\n\r\n")} {
+		n, err := r.spool.Write(data)
+		r.size += int64(n)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	r.responses = append(r.responses, response)
+	return nil
+}
+
+// Responses returns the recorded responses in the order they were received.
+func (r *recorder) Responses() []*recordedResponse {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	return append([]*recordedResponse{}, r.responses...)
+}
+
+// read returns the headers and the body of the recorded response.
+func (r *recorder) read(response *recordedResponse) ([]byte, []byte, error) {
+	data := make([]byte, response.headerSize+response.Size)
+	if _, err := r.spool.ReadAt(data, response.offset); err != nil {
+		return nil, nil, err
+	}
+
+	return data[:response.headerSize], data[response.headerSize:], nil
+}
+
+// writeWARC writes the recorded responses as WARC file to w.
+func (r *recorder) writeWARC(w io.Writer) error {
+	r.m.Lock()
+	size := r.size
+	r.m.Unlock()
+
+	_, err := io.Copy(w, io.NewSectionReader(r.spool, 0, size))
+	return err
+}
+
+// Close removes the spool of the recorded responses.
+func (r *recorder) Close() error {
+	r.spool.Close()
+	return os.Remove(r.spool.Name())
+}
+
+// httpHeader returns the status line and headers of the response as http
+// message, with the headers as received. The transport only leaves out the
+// framing of the body, like chunked transfer encoding, and the encoding of
+// bodies it decompressed itself.
+func (r *recordedResponse) httpHeader() []byte {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "HTTP/1.1 %03d %s\r\n", r.StatusCode, http.StatusText(r.StatusCode))
+	r.Header.Write(buf)
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+// EvidenceScan is a scan of which the responses have been stored.
+type EvidenceScan struct {
+	URL         string    `json:"url"`
+	Application string    `json:"application"`
+	Time        time.Time `json:"time"`
+
+	// Probes are the names of the non-existent files requested to
+	// fingerprint not found pages, per extension
+	Probes map[string]string `json:"probes,omitempty"`
+
+	// Responses is the WARC file with the responses of the scan
+	Responses string `json:"-"`
+}
+
+// Evidence stores the raw responses of scans, per target, as WARC files in
+// a directory, so they can be analyzed again with Capture.
+type Evidence struct {
+	path string
+}
+
+// OpenEvidence opens the evidence directory path, creating it if it doesn't
+// exist.
+func OpenEvidence(path string) (*Evidence, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, err
+	}
+
+	return &Evidence{
+		path: path,
+	}, nil
+}
+
+// Save stores the recorded responses of the scan, in a directory of the
+// target named after the time of the scan. Stored scans are never
+// overwritten.
+func (e *Evidence) Save(scan *EvidenceScan, r *recorder) error {
+	target := filepath.Join(e.path, hashStr(scan.URL))
+	if err := os.MkdirAll(target, 0700); err != nil {
+		return err
+	}
+
+	dir := filepath.Join(target, scan.Time.UTC().Format(evidenceTimeFormat))
+	if err := os.Mkdir(dir, 0700); os.IsExist(err) {
+		return fmt.Errorf("Scan %s has already been stored", filepath.Base(dir))
+	} else if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, "responses.warc"), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	if err := r.writeWARC(f); err != nil {
+		return err
+	} else if err := f.Close(); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(scan, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, "scan.json"), data, 0600)
+}
+
+// Scans returns the stored scans of the target url, oldest first.
+func (e *Evidence) Scans(url string) ([]*EvidenceScan, error) {
+	dir := filepath.Join(e.path, hashStr(url))
+
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []*EvidenceScan{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	scans := []*EvidenceScan{}
+
+	for _, fi := range files {
+		if !fi.IsDir() {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, fi.Name(), "scan.json"))
+		if os.IsNotExist(err) {
+			// incomplete scan
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		scan := &EvidenceScan{}
+		if err := json.Unmarshal(data, scan); err != nil {
+			return nil, err
+		}
+
+		scan.Responses = filepath.Join(dir, fi.Name(), "responses.warc")
+		scans = append(scans, scan)
+	}
+
+	sort.Slice(scans, func(i, j int) bool {
+		return scans[i].Time.Before(scans[j].Time)
+	})
+
+	return scans, nil
+}
+
+// Latest returns the latest stored scan of every target, ordered by url.
+func (e *Evidence) Latest() ([]*EvidenceScan, error) {
+	files, err := ioutil.ReadDir(e.path)
+	if err != nil {
+		return nil, err
+	}
+
+	latest := []*EvidenceScan{}
+
+	for _, fi := range files {
+		if !fi.IsDir() {
+			continue
+		}
+
+		subdirs, err := ioutil.ReadDir(filepath.Join(e.path, fi.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		// scans are named after their time, the last one is the latest
+		for i := len(subdirs) - 1; i >= 0; i-- {
+			data, err := ioutil.ReadFile(filepath.Join(e.path, fi.Name(), subdirs[i].Name(), "scan.json"))
+			if os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+
+			scan := &EvidenceScan{}
+			if err := json.Unmarshal(data, scan); err != nil {
+				return nil, err
+			}
+
+			scan.Responses = filepath.Join(e.path, fi.Name(), subdirs[i].Name(), "responses.warc")
+			latest = append(latest, scan)
+			break
+		}
+	}
+
+	sort.Slice(latest, func(i, j int) bool {
+		return latest[i].URL < latest[j].URL
+	})
+
+	return latest, nil
+}
+
+// record records the responses of the identification, the returned function
+// removes the recorded responses.
+func (b *identify) record() (func(), error) {
+	transport := b.client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	r, err := newRecorder(transport, b.maxBodySize)
+	if err != nil {
+		return nil, err
+	}
+
+	b.recorder = r
+	b.client.Transport = r
+
+	return func() {
+		r.Close()
+	}, nil
+}
+
+// saveEvidence stores the recorded responses in the evidence directory.
+func (b *identify) saveEvidence(target string) {
+	responses := b.recorder.Responses()
+	if len(responses) == 0 {
+		return
+	}
+
+	scan := &EvidenceScan{
+		URL:         target,
+		Application: b.targetApplication,
+		Time:        b.started,
+		Probes:      b.probes,
+	}
+
+	if e, err := OpenEvidence(b.evidence); err != nil {
+		fmt.Fprintln(b.out, color.RedString("[!] Could not open evidence directory: %s", err.Error()))
+	} else if err := e.Save(scan, b.recorder); err != nil {
+		fmt.Fprintln(b.out, color.RedString("[!] Could not save evidence: %s", err.Error()))
+	} else {
+		fmt.Fprintln(b.out, color.YellowString("[+] Saved %d responses as evidence", len(responses)))
+	}
+}
@@ -0,0 +1,328 @@
+package app
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readBundle(t *testing.T, name string) map[string]string {
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{}
+
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		data, _ := ioutil.ReadAll(tr)
+		files[hdr.Name] = string(data)
+	}
+}
+
+func TestEvidence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "identify")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	archives := filepath.Join(dir, "archives")
+	os.Mkdir(archives, 0700)
+
+	writeZip(t, filepath.Join(archives, "application-1.0.0.zip"), map[string]string{
+		"application-1.0.0/readme.txt":    "1.0.0",
+		"application-1.0.0/css/style.css": "body {}",
+	})
+
+	writeZip(t, filepath.Join(archives, "application-1.1.0.zip"), map[string]string{
+		"application-1.1.0/readme.txt":    "1.1.0",
+		"application-1.1.0/css/style.css": "body {}",
+	})
+
+	docroot := filepath.Join(dir, "docroot")
+	writeFiles(t, docroot, map[string]string{
+		"readme.txt":    "1.1.0",
+		"css/style.css": "body {}",
+	})
+
+	// unknown paths are answered with a soft not found page
+	fs := http.FileServer(http.Dir(docroot))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := os.Stat(filepath.Join(docroot, filepath.FromSlash(r.URL.Path))); err == nil {
+			fs.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html>Not found</html>")
+	}))
+
+	scan := func(options ...OptionFn) *identify {
+		u, _ := url.Parse(ts.URL + "/")
+
+		b := newTestIdentify(t, dir, &Application{
+			Name:       "application",
+			Type:       SourceArchive,
+			Repository: archives,
+			Files:      []string{"readme.txt", "css/style.css", "docs/missing.html"},
+		})
+
+		b.targetURL = u
+		b.targetApplication = "application"
+
+		for _, fn := range options {
+			if err := fn(b); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := b.Identify(); err != nil {
+			t.Fatal(err)
+		}
+
+		if best := b.Report().Best(); best == nil || best.Version != "1.1.0" {
+			t.Fatalf("Expected version 1.1.0, got %#v", best)
+		} else if _, ok := b.hashes["docs/missing.html"]; ok {
+			t.Fatalf("Expected soft not found page to be ignored")
+		}
+
+		return b
+	}
+
+	fn, _ := StoreEvidence(filepath.Join(dir, "evidence"))
+	bundle, _ := Bundle(filepath.Join(dir, "bundle.tar.gz"))
+	b := scan(fn, bundle)
+
+	if p := b.Report().Provenance; p == nil || p.Fetched == nil || len(p.Addresses) != 1 || p.Addresses[0] != "127.0.0.1" {
+		t.Errorf("Unexpected provenance: %#v", p)
+	}
+
+	files := readBundle(t, filepath.Join(dir, "bundle.tar.gz"))
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "bundle.tar.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checksum := fmt.Sprintf("%x", sha256.Sum256(data))
+
+	if data, err := ioutil.ReadFile(filepath.Join(dir, "bundle.tar.gz.sha256")); err != nil {
+		t.Fatal(err)
+	} else if string(data) != checksum+"  bundle.tar.gz\n" {
+		t.Errorf("Unexpected checksum file: %q", data)
+	}
+
+	if r := b.Report().Bundle; r == nil || r.SHA256 != checksum {
+		t.Errorf("Expected checksum of bundle in report, got %#v", r)
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(files["SHA256SUMS"]), "\n") {
+		parts := strings.SplitN(line, "  ", 2)
+		if len(parts) != 2 {
+			t.Fatalf("Invalid checksum line: %s", line)
+		} else if data, ok := files[parts[1]]; !ok {
+			t.Errorf("Checksum of missing file %s", parts[1])
+		} else if fmt.Sprintf("%x", sha256.Sum256([]byte(data))) != parts[0] {
+			t.Errorf("Checksum mismatch of %s", parts[1])
+		}
+	}
+
+	manifest := bundleManifest{}
+	if err := json.Unmarshal([]byte(files["manifest.json"]), &manifest); err != nil {
+		t.Fatal(err)
+	} else if manifest.Version != "1.1.0" || manifest.Target != ts.URL+"/" {
+		t.Errorf("Unexpected manifest: %#v", manifest)
+	}
+
+	found := false
+	for _, r := range manifest.Responses {
+		if r.URL != ts.URL+"/readme.txt" {
+			continue
+		}
+
+		found = true
+
+		if files[r.Body] != "1.1.0" || r.SHA1 != fmt.Sprintf("%x", sha1.Sum([]byte("1.1.0"))) {
+			t.Errorf("Unexpected response in bundle: %#v", r)
+		} else if !strings.HasPrefix(files[r.Headers], "HTTP/1.1 200 OK\r\n") {
+			t.Errorf("Unexpected headers in bundle: %q", files[r.Headers])
+		}
+	}
+
+	if !found {
+		t.Errorf("Expected readme.txt in bundle")
+	}
+
+	evidence, err := OpenEvidence(filepath.Join(dir, "evidence"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scans, err := evidence.Latest()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(scans) != 1 || scans[0].URL != ts.URL+"/" || scans[0].Application != "application" {
+		t.Fatalf("Unexpected scans: %#v", scans)
+	}
+
+	if scans[0].Probes[".html"] == "" {
+		t.Fatalf("Expected not found probes to be stored, got %v", scans[0].Probes)
+	}
+
+	// reanalyze without the target, requesting the stored probes
+	ts.Close()
+
+	fn, err = Capture(scans[0].Responses)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	probes, _ := NotFoundProbes(scans[0].Probes)
+	scan(probes, fn)
+}
+
+func TestEvidenceSaveUnique(t *testing.T) {
+	dir, err := ioutil.TempDir("", "identify")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	r, err := newRecorder(redirectTransport{}, defaultMaxBodySize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer r.Close()
+
+	client := &http.Client{Transport: r}
+	if _, err := client.Get("http://example.com/readme.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	evidence, err := OpenEvidence(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	// scans in the same second are stored separately, the same scan is
+	// never overwritten
+	for i, offset := range []time.Duration{0, time.Millisecond, time.Millisecond} {
+		scan := &EvidenceScan{
+			URL:  "http://example.com/",
+			Time: started.Add(offset),
+		}
+
+		if err := evidence.Save(scan, r); (err == nil) != (i < 2) {
+			t.Errorf("Save %d: expected success %t, got %v", i, i < 2, err)
+		}
+	}
+
+	scans, err := evidence.Scans("http://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(scans) != 2 {
+		t.Fatalf("Expected 2 scans, got %d", len(scans))
+	}
+
+	if data, err := ioutil.ReadFile(scans[1].Responses); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(string(data), "WARC-Target-URI: http://example.com/readme.txt\r\n") {
+		t.Errorf("Expected the recorded response in the WARC file:\n%s", data)
+	}
+}
+
+// headerTransport returns responses with the header and body.
+type headerTransport struct {
+	header http.Header
+	body   string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     t.header,
+		Body:       ioutil.NopCloser(strings.NewReader(t.body)),
+		Request:    req,
+	}, nil
+}
+
+func TestRecorderHeaders(t *testing.T) {
+	// a body that hasn't been decompressed keeps its encoding, and is
+	// truncated after the maximum size
+	r, err := newRecorder(&headerTransport{
+		header: http.Header{
+			"Content-Encoding": []string{"br"},
+			"Content-Length":   []string{"10"},
+		},
+		body: "0123456789",
+	}, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer r.Close()
+
+	client := &http.Client{Transport: r}
+	if _, err := client.Get("http://example.com/style.css"); err != nil {
+		t.Fatal(err)
+	}
+
+	headers, body, err := r.read(r.Responses()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "HTTP/1.1 200 OK\r\nContent-Encoding: br\r\nContent-Length: 10\r\n\r\n"; string(headers) != expected {
+		t.Errorf("Expected headers as received %q, got %q", expected, headers)
+	} else if string(body) != "01234" {
+		t.Errorf("Expected truncated body, got %q", body)
+	}
+
+	// the truncated response can still be analyzed
+	buf := &bytes.Buffer{}
+	if err := r.writeWARC(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	ct := &captureTransport{responses: map[string]*capturedResponse{}}
+	if err := ct.readWARC(buf); err != nil {
+		t.Fatal(err)
+	} else if len(ct.responses) != 1 {
+		t.Errorf("Expected the truncated response to be read, got %d responses", len(ct.responses))
+	}
+}
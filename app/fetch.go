@@ -0,0 +1,160 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultTimeout = 30 * time.Second
+	defaultRetries = 2
+	defaultBackoff = time.Second
+
+	// maxRetryAfter is the longest delay asked for by a server that will be
+	// honored
+	maxRetryAfter = time.Minute
+
+	defaultMaxBodySize = 10 * 1024 * 1024
+)
+
+// maxDrainSize is the maximum number of bytes read from a body that will be
+// discarded, to allow the connection to be reused without reading forever.
+const maxDrainSize = 64 * 1024
+
+// closeBody drains and closes the body of the response.
+func closeBody(resp *http.Response) {
+	io.CopyN(ioutil.Discard, resp.Body, maxDrainSize)
+	resp.Body.Close()
+}
+
+// readBody reads the body of the response, up to max bytes, and closes it.
+func readBody(resp *http.Response, max int64) ([]byte, error) {
+	defer closeBody(resp)
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, max+1))
+	if err != nil {
+		return nil, err
+	} else if int64(len(body)) > max {
+		return nil, fmt.Errorf("Body exceeds maximum size of %d bytes", max)
+	}
+
+	return body, nil
+}
+
+// retryAfter returns the delay the server asked for in the Retry-After
+// header, which contains either a number of seconds or a http date, up to
+// maxRetryAfter.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+
+	d := time.Duration(0)
+
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		d = time.Duration(seconds) * time.Second
+	} else if t, err := http.ParseTime(v); err == nil {
+		d = time.Until(t)
+	} else {
+		return 0, false
+	}
+
+	if d < 0 {
+		d = 0
+	} else if d > maxRetryAfter {
+		d = maxRetryAfter
+	}
+
+	return d, true
+}
+
+func shouldRetry(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable
+}
+
+func (b *identify) context() context.Context {
+	if b.ctx == nil {
+		return context.Background()
+	}
+
+	return b.ctx
+}
+
+func (b *identify) get(u string) (*http.Response, error) {
+	return b.fetch(b.client, u)
+}
+
+// fetchSource gets the url of the database or a source, like a release
+// archive, with the proxy, timeout and retries of the identification, but
+// not with the transport and redirect policy of the target.
+func (b *identify) fetchSource(u string) (*http.Response, error) {
+	client := &http.Client{
+		Transport: b.transport,
+		Timeout:   b.client.Timeout,
+	}
+
+	return b.fetch(client, u)
+}
+
+// fetch gets the url using client, retrying with exponential backoff on
+// network errors and on 429 and 503 responses.
+func (b *identify) fetch(client *http.Client, u string) (*http.Response, error) {
+	ctx := b.context()
+
+	backoff := b.backoff
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest("GET", u, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		start := time.Now()
+
+		resp, err := client.Do(req.WithContext(ctx))
+
+		b.metrics.observe(metricFetchDuration, time.Since(start).Seconds())
+
+		if err != nil {
+			b.metrics.inc(metricFetchErrors)
+		} else {
+			b.metrics.inc(metricResponses, "code", strconv.Itoa(resp.StatusCode))
+		}
+
+		if err != nil && ctx.Err() != nil {
+			return nil, fmt.Errorf("Overall timeout exceeded: %s", ctx.Err().Error())
+		} else if attempt >= b.retries {
+			return resp, err
+		} else if err != nil {
+		} else if !shouldRetry(resp) {
+			return resp, nil
+		}
+
+		wait := backoff
+		if err == nil {
+			if d, ok := retryAfter(resp); ok {
+				wait = d
+			}
+
+			closeBody(resp)
+		}
+
+		if b.debug {
+			fmt.Fprintf(b.out, "[ ] Retrying %s in %s\n", u, wait)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("Overall timeout exceeded: %s", ctx.Err().Error())
+		case <-time.After(wait):
+		}
+
+		backoff *= 2
+	}
+}
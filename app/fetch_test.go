@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFetchRetry(t *testing.T) {
+	requests := 0
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		if requests < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	b := &identify{
+		client: ts.Client(),
+	}
+	b.retries = 2
+	b.backoff = time.Hour
+
+	resp, err := b.get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	if requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", requests)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+
+	if _, ok := retryAfter(resp); ok {
+		t.Error("Expected no delay without Retry-After header")
+	}
+
+	resp.Header.Set("Retry-After", "30")
+
+	if d, ok := retryAfter(resp); !ok || d != 30*time.Second {
+		t.Errorf("Expected delay of 30s, got %s", d)
+	}
+
+	resp.Header.Set("Retry-After", "86400")
+
+	if d, ok := retryAfter(resp); !ok || d != maxRetryAfter {
+		t.Errorf("Expected delay of %s, got %s", maxRetryAfter, d)
+	}
+
+	resp.Header.Set("Retry-After", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+
+	if d, ok := retryAfter(resp); !ok || d != 0 {
+		t.Errorf("Expected no delay for date in the past, got %s", d)
+	}
+}
+
+func TestFetchCanceled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	b := &identify{
+		client: ts.Client(),
+		ctx:    ctx,
+	}
+	b.retries = 2
+
+	start := time.Now()
+
+	if _, err := b.get(ts.URL); err == nil {
+		t.Error("Expected error when canceled while waiting to retry")
+	} else if time.Since(start) > 5*time.Second {
+		t.Errorf("Expected wait to be canceled, took %s", time.Since(start))
+	}
+}
+
+func TestReadBodyMaxSize(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 1024))
+	}))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := readBody(resp, 512); err == nil {
+		t.Error("Expected error for body exceeding maximum size")
+	}
+
+	resp, err = http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if body, err := readBody(resp, 1024); err != nil {
+		t.Error(err)
+	} else if len(body) != 1024 {
+		t.Errorf("Expected body of 1024 bytes, got %d", len(body))
+	}
+}
@@ -0,0 +1,209 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+const (
+	HookScanCompleted = "scan-completed"
+	HookAdvisories    = "advisories"
+)
+
+const defaultHookTimeout = 30 * time.Second
+
+// Notification is the payload sent to hooks.
+type Notification struct {
+	Event      string      `json:"event"`
+	Time       time.Time   `json:"time"`
+	Target     string      `json:"target"`
+	Change     *Event      `json:"change,omitempty"`
+	Advisories []*Advisory `json:"advisories,omitempty"`
+	Report     *Report     `json:"report"`
+}
+
+// Hooks posts notifications as json to webhooks and passes them on stdin to
+// commands.
+type Hooks struct {
+	webhooks []string
+	commands []string
+	events   map[string]bool
+
+	client  *http.Client
+	timeout time.Duration
+}
+
+// NewHooks returns hooks for the webhook urls and the shell commands,
+// notifying only of the events when not empty.
+func NewHooks(webhooks []string, commands []string, events []string) (*Hooks, error) {
+	for _, webhook := range webhooks {
+		if u, err := url.Parse(webhook); err != nil {
+			return nil, err
+		} else if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("Invalid webhook url: %s", webhook)
+		}
+	}
+
+	h := &Hooks{
+		webhooks: webhooks,
+		commands: commands,
+		events:   map[string]bool{},
+		client: &http.Client{
+			Timeout: defaultHookTimeout,
+		},
+		timeout: defaultHookTimeout,
+	}
+
+	for _, event := range events {
+		switch event {
+		case HookScanCompleted, HookAdvisories, EventVersionChanged, EventVersionRegressed, EventInconsistent:
+			h.events[event] = true
+		default:
+			return nil, fmt.Errorf("Unknown hook event: %s", event)
+		}
+	}
+
+	return h, nil
+}
+
+func (h *Hooks) post(webhook string, data []byte) error {
+	resp, err := h.client.Post(webhook, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	closeBody(resp)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("Webhook returned status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (h *Hooks) run(command string, n *Notification, data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+
+	output := &bytes.Buffer{}
+
+	cmd := hookCommand(command)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	cmd.Env = append(os.Environ(),
+		"IDENTIFY_EVENT="+n.Event,
+		"IDENTIFY_TARGET="+n.Target,
+	)
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("%s: %s", err.Error(), strings.TrimSpace(output.String()))
+		}
+
+		return nil
+	case <-ctx.Done():
+		// children of the shell would keep the output open after only the
+		// shell has been killed
+		killHook(cmd)
+		<-done
+
+		return fmt.Errorf("Command timed out after %s", h.timeout)
+	}
+}
+
+// Notify sends the notification to all webhooks and commands, writing errors
+// to out.
+func (h *Hooks) Notify(out io.Writer, n *Notification) {
+	if h == nil {
+		return
+	} else if len(h.events) > 0 && !h.events[n.Event] {
+		return
+	}
+
+	data, err := json.Marshal(n)
+	if err != nil {
+		fmt.Fprintln(out, color.RedString("[!] Could not encode notification: %s", err.Error()))
+		return
+	}
+
+	for _, webhook := range h.webhooks {
+		if err := h.post(webhook, data); err != nil {
+			fmt.Fprintln(out, color.RedString("[!] Could not notify webhook %s: %s", webhook, err.Error()))
+		}
+	}
+
+	for _, command := range h.commands {
+		if err := h.run(command, n, data); err != nil {
+			fmt.Fprintln(out, color.RedString("[!] Could not run hook %s: %s", command, err.Error()))
+		}
+	}
+}
+
+// advisories returns the advisories of the best matching versions of the
+// report and its components.
+func (r *Report) advisories() []*Advisory {
+	advisories := []*Advisory{}
+
+	if best := r.Best(); best != nil {
+		advisories = append(advisories, best.Advisories...)
+	}
+
+	for _, component := range r.Components {
+		advisories = append(advisories, component.advisories()...)
+	}
+
+	return advisories
+}
+
+// NotifyReport notifies that the scan of the report completed, and of the
+// advisories of the identified versions.
+func (h *Hooks) NotifyReport(out io.Writer, report *Report) {
+	h.Notify(out, &Notification{
+		Event:  HookScanCompleted,
+		Time:   time.Now(),
+		Target: report.Target,
+		Report: report,
+	})
+
+	if advisories := report.advisories(); len(advisories) > 0 {
+		h.Notify(out, &Notification{
+			Event:      HookAdvisories,
+			Time:       time.Now(),
+			Target:     report.Target,
+			Advisories: advisories,
+			Report:     report,
+		})
+	}
+}
+
+// NotifyEvent notifies of the event raised by the monitor for the report.
+func (h *Hooks) NotifyEvent(out io.Writer, event *Event, report *Report) {
+	h.Notify(out, &Notification{
+		Event:  event.Type,
+		Time:   event.Time,
+		Target: event.URL,
+		Change: event,
+		Report: report,
+	})
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+// +build !windows
+
+package app
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// hookCommand returns the shell running command in its own process group,
+// so the children of the shell can be killed with it.
+func hookCommand(command string) *exec.Cmd {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return cmd
+}
+
+// killHook kills the process group of the started command.
+func killHook(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHooks(t *testing.T) {
+	notifications := []*Notification{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := &Notification{}
+		if err := json.NewDecoder(r.Body).Decode(n); err != nil {
+			t.Error(err)
+		}
+
+		notifications = append(notifications, n)
+	}))
+
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "identify")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "notification.json")
+
+	h, err := NewHooks([]string{server.URL}, []string{"cat > " + name}, []string{HookAdvisories})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := testReport()
+	report.Versions[0].Advisories = []*Advisory{{ID: "CVE-2017-1001"}}
+
+	h.NotifyReport(ioutil.Discard, report)
+
+	if len(notifications) != 1 {
+		t.Fatalf("Expected 1 notification, got %d", len(notifications))
+	} else if n := notifications[0]; n.Event != HookAdvisories || len(n.Advisories) != 1 || n.Report == nil {
+		t.Errorf("Unexpected notification: %#v", n)
+	}
+
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := &Notification{}
+	if err := json.Unmarshal(data, n); err != nil {
+		t.Fatal(err)
+	} else if n.Target != report.Target {
+		t.Errorf("Unexpected target: %s", n.Target)
+	}
+
+	if _, err := NewHooks(nil, nil, []string{"unknown"}); err == nil {
+		t.Errorf("Expected error for unknown event")
+	}
+}
+
+func TestHooksTimeout(t *testing.T) {
+	h, err := NewHooks(nil, []string{"sleep 10"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.timeout = 100 * time.Millisecond
+
+	start := time.Now()
+
+	if err := h.run("sleep 10", &Notification{}, nil); err == nil {
+		t.Errorf("Expected command to time out")
+	} else if time.Since(start) > 5*time.Second {
+		t.Errorf("Expected command to be killed, took %s", time.Since(start))
+	}
+}
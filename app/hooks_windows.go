@@ -0,0 +1,15 @@
+package app
+
+import (
+	"os/exec"
+)
+
+// hookCommand returns the shell running command.
+func hookCommand(command string) *exec.Cmd {
+	return exec.Command("sh", "-c", command)
+}
+
+// killHook kills the started command.
+func killHook(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,100 @@
+package app
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// CPE contains the vendor and product of the application in the CPE
+// dictionary, used to build CPE 2.3 names for identified versions.
+type CPE struct {
+	Part    string `yaml:"part"`
+	Vendor  string `yaml:"vendor"`
+	Product string `yaml:"product"`
+}
+
+// Purl contains the type, namespace and name of the package url of the
+// application. When the type is empty, a github package url is derived from
+// the repository.
+type Purl struct {
+	Type      string `yaml:"type"`
+	Namespace string `yaml:"namespace"`
+	Name      string `yaml:"name"`
+}
+
+// escapeCPE quotes all characters that are not allowed unquoted in a
+// component of a CPE 2.3 formatted string.
+func escapeCPE(s string) string {
+	if s == "" {
+		return "*"
+	}
+
+	escaped := ""
+	for _, c := range strings.ToLower(s) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '_', c == '-', c == '.':
+			escaped += string(c)
+		default:
+			escaped += "\\" + string(c)
+		}
+	}
+
+	return escaped
+}
+
+// CPEName returns the CPE 2.3 formatted string for version of the
+// application, or an empty string if the application has no CPE.
+func (a *Application) CPEName(version string) string {
+	if a.CPE.Vendor == "" || a.CPE.Product == "" {
+		return ""
+	}
+
+	part := a.CPE.Part
+	if part == "" {
+		part = "a"
+	}
+
+	version = strings.TrimPrefix(version, "v")
+
+	return fmt.Sprintf("cpe:2.3:%s:%s:%s:%s:*:*:*:*:*:*:*", part, escapeCPE(a.CPE.Vendor), escapeCPE(a.CPE.Product), escapeCPE(version))
+}
+
+// PackageURL returns the package url for version of the application, or an
+// empty string if the application has no package url.
+func (a *Application) PackageURL(version string) string {
+	purl := a.Purl
+
+	if purl.Type != "" {
+	} else if u, err := url.Parse(a.Repository); err != nil || u.Host != "github.com" {
+		return ""
+	} else if parts := strings.Split(strings.Trim(u.Path, "/"), "/"); len(parts) != 2 {
+		return ""
+	} else {
+		purl = Purl{
+			Type:      "github",
+			Namespace: strings.ToLower(parts[0]),
+			Name:      strings.ToLower(strings.TrimSuffix(parts[1], ".git")),
+		}
+	}
+
+	if purl.Name == "" {
+		return ""
+	}
+
+	s := "pkg:" + strings.ToLower(purl.Type) + "/"
+
+	if purl.Namespace != "" {
+		for _, segment := range strings.Split(purl.Namespace, "/") {
+			s += url.PathEscape(segment) + "/"
+		}
+	}
+
+	s += url.PathEscape(purl.Name)
+
+	if version != "" {
+		s += "@" + url.PathEscape(version)
+	}
+
+	return s
+}
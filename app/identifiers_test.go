@@ -0,0 +1,36 @@
+package app
+
+import "testing"
+
+func TestCPEName(t *testing.T) {
+	a := &Application{
+		CPE: CPE{Vendor: "joomla", Product: "joomla!"},
+	}
+
+	if s := a.CPEName("3.6.4"); s != `cpe:2.3:a:joomla:joomla\!:3.6.4:*:*:*:*:*:*:*` {
+		t.Errorf("Unexpected cpe: %s", s)
+	}
+
+	if s := (&Application{}).CPEName("3.6.4"); s != "" {
+		t.Errorf("Expected no cpe, got %s", s)
+	}
+}
+
+func TestPackageURL(t *testing.T) {
+	a := &Application{
+		Repository: "https://github.com/WordPress/WordPress",
+	}
+
+	if s := a.PackageURL("4.7.1"); s != "pkg:github/wordpress/wordpress@4.7.1" {
+		t.Errorf("Unexpected purl: %s", s)
+	}
+
+	a = &Application{
+		Repository: "https://plugins.svn.wordpress.org/akismet",
+		Purl:       Purl{Type: "composer", Namespace: "wpackagist-plugin", Name: "akismet"},
+	}
+
+	if s := a.PackageURL("3.3"); s != "pkg:composer/wpackagist-plugin/akismet@3.3" {
+		t.Errorf("Unexpected purl: %s", s)
+	}
+}
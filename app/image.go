@@ -0,0 +1,426 @@
+package app
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+const (
+	whiteoutPrefix = ".wh."
+	whiteoutOpaque = ".wh..wh..opq"
+)
+
+// dockerManifest is an image in the manifest.json of docker save.
+type dockerManifest struct {
+	Config   string
+	RepoTags []string
+	Layers   []string
+}
+
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+}
+
+// ociManifest is an OCI image index or image manifest.
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Manifests []ociDescriptor `json:"manifests"`
+	Layers    []ociDescriptor `json:"layers"`
+}
+
+// ociBlob returns the path of the blob with digest in the OCI layout dir.
+func ociBlob(dir string, digest string) (string, error) {
+	parts := strings.SplitN(digest, ":", 2)
+	if len(parts) != 2 || strings.ContainsAny(parts[0]+parts[1], `/\.`) {
+		return "", fmt.Errorf("Invalid digest: %s", digest)
+	}
+
+	return filepath.Join(dir, "blobs", parts[0], parts[1]), nil
+}
+
+// ociLayers returns the layers of the first image of the OCI index at name.
+func ociLayers(dir string, name string) ([]string, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest ociManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+
+	if len(manifest.Manifests) > 0 {
+		// index, continue with the first image
+		blob, err := ociBlob(dir, manifest.Manifests[0].Digest)
+		if err != nil {
+			return nil, err
+		}
+
+		return ociLayers(dir, blob)
+	}
+
+	layers := []string{}
+	for _, layer := range manifest.Layers {
+		blob, err := ociBlob(dir, layer.Digest)
+		if err != nil {
+			return nil, err
+		}
+
+		layers = append(layers, blob)
+	}
+
+	return layers, nil
+}
+
+// imageLayers returns the layers of the image in dir, which is the contents
+// of a docker save tarball or an OCI layout.
+func imageLayers(dir string) ([]string, error) {
+	if data, err := ioutil.ReadFile(filepath.Join(dir, "manifest.json")); err == nil {
+		manifests := []dockerManifest{}
+		if err := json.Unmarshal(data, &manifests); err != nil {
+			return nil, err
+		} else if len(manifests) == 0 {
+			return nil, fmt.Errorf("No images in manifest")
+		}
+
+		layers := []string{}
+		for _, layer := range manifests[0].Layers {
+			p, err := extractPath(dir, layer)
+			if err != nil {
+				return nil, err
+			}
+
+			layers = append(layers, p)
+		}
+
+		return layers, nil
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "index.json")); err == nil {
+		return ociLayers(dir, filepath.Join(dir, "index.json"))
+	}
+
+	return nil, fmt.Errorf("Not a docker save tarball or OCI layout")
+}
+
+// applyLayer extracts the layer, optionally gzip compressed, into rootfs,
+// removing the files that have been whited out. Links aren't extracted, as
+// they could point outside of rootfs.
+func applyLayer(layer string, rootfs string) error {
+	f, err := os.Open(layer)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+
+	var r io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		if r, err = gzip.NewReader(br); err != nil {
+			return err
+		}
+	}
+
+	tr := tar.NewReader(r)
+
+	// the files of this layer, which aren't removed by opaque whiteouts
+	extracted := map[string]bool{}
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		name := path.Clean("/" + hdr.Name)
+		if name == "/" {
+			continue
+		}
+
+		p, err := extractPath(rootfs, name[1:])
+		if err != nil {
+			return err
+		}
+
+		dir, base := filepath.Split(p)
+
+		if base == whiteoutOpaque {
+			if err := removeLower(filepath.Clean(dir), extracted); err != nil {
+				return err
+			}
+
+			continue
+		} else if strings.HasPrefix(base, whiteoutPrefix) {
+			if err := os.RemoveAll(filepath.Join(dir, strings.TrimPrefix(base, whiteoutPrefix))); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		// the directories containing the file are kept as well
+		for d := p; d != filepath.Clean(rootfs) && !extracted[d]; d = filepath.Dir(d) {
+			extracted[d] = true
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if fi, err := os.Lstat(p); err == nil && !fi.IsDir() {
+				os.Remove(p)
+			}
+
+			if err := os.MkdirAll(p, 0700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.RemoveAll(p); err != nil {
+				return err
+			} else if err := extractFile(p, tr); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			target, err := extractPath(rootfs, path.Clean("/" + hdr.Linkname)[1:])
+			if err != nil {
+				return err
+			}
+
+			src, err := os.Open(target)
+			if os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				return err
+			}
+
+			err = os.RemoveAll(p)
+			if err == nil {
+				err = extractFile(p, src)
+			}
+
+			src.Close()
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// removeLower removes the files in dir that haven't been extracted from the
+// current layer, keeping the directories that contain such files.
+func removeLower(dir string, extracted map[string]bool) error {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, fi := range files {
+		p := filepath.Join(dir, fi.Name())
+
+		if !extracted[p] {
+			err = os.RemoveAll(p)
+		} else if fi.IsDir() {
+			err = removeLower(p, extracted)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// extractImage flattens the layers of the image at location, a docker save
+// tarball or an OCI layout directory, into rootfs.
+func extractImage(location string, rootfs string) error {
+	fi, err := os.Stat(location)
+	if err != nil {
+		return err
+	}
+
+	dir := location
+
+	if !fi.IsDir() {
+		tmp, err := ioutil.TempDir("", "identify-image")
+		if err != nil {
+			return err
+		}
+
+		defer os.RemoveAll(tmp)
+
+		if err := extractTar(location, tmp, nil); err != nil {
+			return err
+		}
+
+		dir = tmp
+	}
+
+	layers, err := imageLayers(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, layer := range layers {
+		if err := applyLayer(layer, rootfs); err != nil {
+			return fmt.Errorf("Could not apply layer %s: %s", filepath.Base(layer), err.Error())
+		}
+	}
+
+	return nil
+}
+
+// locateRoot returns the directory in rootfs that contains most files of the
+// application, relative to rootfs, and the number of files found.
+func locateRoot(rootfs string, application *Application) (string, int, error) {
+	counts := map[string]int{}
+
+	err := filepath.Walk(rootfs, func(name string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		} else if !fi.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(rootfs, name)
+		if err != nil {
+			return err
+		}
+
+		rel = filepath.ToSlash(rel)
+
+		for _, file := range application.Files {
+			file = strings.TrimPrefix(path.Clean("/"+file), "/")
+
+			if rel == file {
+				counts[""]++
+			} else if strings.HasSuffix(rel, "/"+file) {
+				counts[strings.TrimSuffix(rel, file)]++
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return "", 0, err
+	}
+
+	roots := []string{}
+	for root := range counts {
+		roots = append(roots, root)
+	}
+
+	// prefer most files found, then the shortest path
+	sort.Slice(roots, func(i, j int) bool {
+		if counts[roots[i]] != counts[roots[j]] {
+			return counts[roots[i]] > counts[roots[j]]
+		}
+
+		return len(roots[i]) < len(roots[j])
+	})
+
+	if len(roots) == 0 {
+		return "", 0, nil
+	}
+
+	return roots[0], counts[roots[0]], nil
+}
+
+// openImage flattens the image into a temporary directory, and locates the
+// application in it, detecting the application if it hasn't been set. The
+// returned function removes the temporary directory.
+func (b *identify) openImage() (func(), error) {
+	rootfs, err := ioutil.TempDir("", "identify-rootfs")
+	if err != nil {
+		return nil, err
+	}
+
+	cleanup := func() {
+		os.RemoveAll(rootfs)
+	}
+
+	fmt.Fprintln(b.out, color.YellowString("[+] Extracting image %s", b.image))
+
+	if err := extractImage(b.image, rootfs); err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	names := []string{b.targetApplication}
+	if b.application == nil {
+		names = []string{}
+		for name := range b.db.Application {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+	}
+
+	best, bestRoot, bestFound := "", "", 0
+
+	for _, name := range names {
+		application := b.db.Application[name]
+
+		root, found, err := locateRoot(rootfs, &application)
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+
+		if found > bestFound {
+			best, bestRoot, bestFound = name, root, found
+		}
+	}
+
+	if best == "" {
+		cleanup()
+		return nil, fmt.Errorf("Could not locate application in image")
+	}
+
+	application := b.db.Application[best]
+
+	b.targetApplication = best
+	b.application = &application
+	b.rootfs = rootfs
+	b.client.Transport = &fileTransport{root: rootfs}
+	b.targetURL = &url.URL{
+		Scheme: "file",
+		Path:   filepath.ToSlash(filepath.Join(rootfs, filepath.FromSlash(bestRoot))) + "/",
+	}
+
+	fmt.Fprintln(b.out, color.YellowString("[+] Found %s at /%s", best, bestRoot))
+	return cleanup, nil
+}
+
+// target returns the target as reported, the location in the image for
+// images.
+func (b *identify) target() string {
+	if b.image == "" {
+		return b.targetURL.String()
+	} else if b.rootfs == "" {
+		return b.image
+	}
+
+	return b.image + ":/" + strings.TrimPrefix(strings.TrimPrefix(b.targetURL.Path, filepath.ToSlash(b.rootfs)), "/")
+}
@@ -0,0 +1,256 @@
+package app
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// writeBlob writes data as blob of the OCI layout dir, and returns its
+// digest.
+func writeBlob(t *testing.T, dir string, data []byte) string {
+	digest := fmt.Sprintf("%x", sha256.Sum256(data))
+
+	writeFiles(t, dir, map[string]string{
+		"blobs/sha256/" + digest: string(data),
+	})
+
+	return "sha256:" + digest
+}
+
+func tarFiles(t *testing.T, files map[string]string, compress bool) []byte {
+	entries := [][2]string{}
+	for name, content := range files {
+		entries = append(entries, [2]string{name, content})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i][0] < entries[j][0]
+	})
+
+	return tarEntries(t, entries, compress)
+}
+
+// tarEntries returns a tar archive with the name and content pairs as files,
+// in order.
+func tarEntries(t *testing.T, entries [][2]string, compress bool) []byte {
+	buf := &bytes.Buffer{}
+
+	var gw *gzip.Writer
+	tw := tar.NewWriter(buf)
+	if compress {
+		gw = gzip.NewWriter(buf)
+		tw = tar.NewWriter(gw)
+	}
+
+	for _, entry := range entries {
+		name, content := entry[0], entry[1]
+
+		if err := tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		tw.Write([]byte(content))
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if gw != nil {
+		gw.Close()
+	}
+
+	return buf.Bytes()
+}
+
+func TestImage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "identify")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	archives := filepath.Join(dir, "archives")
+	os.Mkdir(archives, 0700)
+
+	writeZip(t, filepath.Join(archives, "application-1.0.0.zip"), map[string]string{
+		"application-1.0.0/readme.txt":    "1.0.0",
+		"application-1.0.0/css/style.css": "body {}",
+	})
+
+	writeZip(t, filepath.Join(archives, "application-1.1.0.zip"), map[string]string{
+		"application-1.1.0/readme.txt":    "1.1.0",
+		"application-1.1.0/css/style.css": "body {}",
+	})
+
+	// the second layer upgrades the application and removes a file
+	image := filepath.Join(dir, "image.tar")
+	f, err := os.Create(image)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.Write(tarFiles(t, map[string]string{
+		"manifest.json": `[{"Config":"config.json","Layers":["1/layer.tar","2/layer.tar"]}]`,
+		"1/layer.tar": string(tarFiles(t, map[string]string{
+			"etc/os-release":             "alpine",
+			"var/www/html/readme.txt":    "1.0.0",
+			"var/www/html/css/style.css": "body {}",
+			"var/www/html/install.php":   "<?php",
+			"usr/share/doc/x/readme.txt": "other",
+		}, false)),
+		"2/layer.tar": string(tarFiles(t, map[string]string{
+			"var/www/html/readme.txt":      "1.1.0",
+			"var/www/html/.wh.install.php": "",
+		}, true)),
+	}, false))
+	f.Close()
+
+	b := newTestIdentify(t, dir, nil)
+	b.db = &DB{
+		Application: map[string]Application{
+			"application": {
+				Name:       "application",
+				Type:       SourceArchive,
+				Repository: archives,
+				Files:      []string{"readme.txt", "css/style.css"},
+			},
+		},
+	}
+
+	fn, err := Image(image)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := fn(b); err != nil {
+		t.Fatal(err)
+	}
+
+	cleanup, err := b.openImage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer cleanup()
+
+	if b.targetApplication != "application" {
+		t.Fatalf("Expected application to be detected, got %s", b.targetApplication)
+	} else if target := b.target(); target != image+":/var/www/html/" {
+		t.Fatalf("Unexpected target: %s", target)
+	}
+
+	if _, err := os.Stat(filepath.Join(b.rootfs, "var/www/html/install.php")); !os.IsNotExist(err) {
+		t.Errorf("Expected whiteout file to be removed")
+	}
+
+	if err := b.identify(); err != nil {
+		t.Fatal(err)
+	}
+
+	if best := b.Report().Best(); best == nil || best.Version != "1.1.0" {
+		t.Fatalf("Expected version 1.1.0, got %#v", best)
+	}
+}
+
+func TestImageOCI(t *testing.T) {
+	dir, err := ioutil.TempDir("", "identify")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	archives := filepath.Join(dir, "archives")
+	os.Mkdir(archives, 0700)
+
+	writeZip(t, filepath.Join(archives, "application-1.0.0.zip"), map[string]string{
+		"application-1.0.0/readme.txt":    "1.0.0",
+		"application-1.0.0/css/style.css": "body {}",
+	})
+
+	writeZip(t, filepath.Join(archives, "application-1.1.0.zip"), map[string]string{
+		"application-1.1.0/readme.txt":    "1.1.0",
+		"application-1.1.0/css/style.css": "body {}",
+	})
+
+	// the second layer upgrades the application and replaces the uploads
+	// with an opaque whiteout, after adding a file of its own
+	layout := filepath.Join(dir, "layout")
+
+	lower := writeBlob(t, layout, tarFiles(t, map[string]string{
+		"var/www/html/readme.txt":           "1.0.0",
+		"var/www/html/css/style.css":        "body {}",
+		"var/www/html/uploads/shell.php":    "<?php",
+		"var/www/html/uploads/2017/old.txt": "old",
+	}, true))
+
+	upper := writeBlob(t, layout, tarEntries(t, [][2]string{
+		{"var/www/html/uploads/2017/new.txt", "new"},
+		{"var/www/html/uploads/.wh..wh..opq", ""},
+		{"var/www/html/readme.txt", "1.1.0"},
+	}, false))
+
+	manifest := writeBlob(t, layout, []byte(fmt.Sprintf(`{"schemaVersion":2,"layers":[{"digest":%q},{"digest":%q}]}`, lower, upper)))
+
+	writeFiles(t, layout, map[string]string{
+		"oci-layout": `{"imageLayoutVersion":"1.0.0"}`,
+		"index.json": fmt.Sprintf(`{"schemaVersion":2,"manifests":[{"digest":%q}]}`, manifest),
+	})
+
+	b := newTestIdentify(t, dir, nil)
+	b.db = &DB{
+		Application: map[string]Application{
+			"application": {
+				Name:       "application",
+				Type:       SourceArchive,
+				Repository: archives,
+				Files:      []string{"readme.txt", "css/style.css"},
+			},
+		},
+	}
+
+	fn, err := Image(layout)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := fn(b); err != nil {
+		t.Fatal(err)
+	}
+
+	cleanup, err := b.openImage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer cleanup()
+
+	for name, exists := range map[string]bool{
+		"var/www/html/uploads/shell.php":    false,
+		"var/www/html/uploads/2017/old.txt": false,
+		"var/www/html/uploads/2017/new.txt": true,
+	} {
+		if _, err := os.Stat(filepath.Join(b.rootfs, filepath.FromSlash(name))); os.IsNotExist(err) == exists {
+			t.Errorf("Expected %s to exist after opaque whiteout: %t", name, exists)
+		}
+	}
+
+	if err := b.identify(); err != nil {
+		t.Fatal(err)
+	}
+
+	if best := b.Report().Best(); best == nil || best.Version != "1.1.0" {
+		t.Fatalf("Expected version 1.1.0, got %#v", best)
+	}
+}
@@ -0,0 +1,264 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Index shares the sources of applications and the hashes of their files
+// between identifications, so a long running process only has to retrieve
+// and hash every version once. Sources are updated at most once every maxAge.
+type Index struct {
+	maxAge time.Duration
+
+	m       sync.Mutex
+	sources map[string]*indexedSource
+}
+
+// NewIndex returns an empty index, updating its sources when they are older
+// than maxAge.
+func NewIndex(maxAge time.Duration) *Index {
+	return &Index{
+		maxAge:  maxAge,
+		sources: map[string]*indexedSource{},
+	}
+}
+
+// indexWriter writes to the output of the identification currently using the
+// source.
+type indexWriter struct {
+	w io.Writer
+}
+
+func (w *indexWriter) Write(p []byte) (int, error) {
+	return w.w.Write(p)
+}
+
+// indexFetcher fetches with the fetch function of the identification
+// currently using the source.
+type indexFetcher struct {
+	fn FetchFn
+}
+
+func (f *indexFetcher) fetch(u string) (*http.Response, error) {
+	if f.fn == nil {
+		return http.Get(u)
+	}
+
+	return f.fn(u)
+}
+
+// indexedSource is a source with its references cached. It is locked while
+// being used by an identification.
+type indexedSource struct {
+	sync.Mutex
+	Source
+
+	maxAge  time.Duration
+	out     *indexWriter
+	fetcher *indexFetcher
+	updated time.Time
+
+	branches []Reference
+	tags     []Reference
+}
+
+// fresh returns true if the source has been updated within the maximum age.
+func (s *indexedSource) fresh() bool {
+	return !s.updated.IsZero() && time.Since(s.updated) < s.maxAge
+}
+
+// Update updates the source if it is older than the maximum age, and indexes
+// its references.
+func (s *indexedSource) Update() error {
+	if s.fresh() {
+		return nil
+	}
+
+	if err := s.Source.Update(); err != nil {
+		return err
+	}
+
+	branches, err := s.Source.Branches()
+	if err != nil {
+		return err
+	}
+
+	tags, err := s.Source.Tags()
+	if err != nil {
+		return err
+	}
+
+	s.branches = indexReferences(branches)
+	s.tags = indexReferences(tags)
+	s.updated = time.Now()
+	return nil
+}
+
+func (s *indexedSource) Branches() ([]Reference, error) {
+	return s.branches, nil
+}
+
+func (s *indexedSource) Tags() ([]Reference, error) {
+	return s.tags, nil
+}
+
+// indexedReference is a reference that remembers the hashes of its files.
+type indexedReference struct {
+	Reference
+
+	hashes map[string][]byte
+}
+
+func (r *indexedReference) Walk(dir string, fn func(name string) error) error {
+	w, ok := r.Reference.(Walker)
+	if !ok {
+		return fmt.Errorf("Reference %s can't list its files", r.Name())
+	}
+
+	return w.Walk(dir, fn)
+}
+
+func indexReferences(refs []Reference) []Reference {
+	indexed := make([]Reference, len(refs))
+	for i, ref := range refs {
+		indexed[i] = &indexedReference{
+			Reference: ref,
+			hashes:    map[string][]byte{},
+		}
+	}
+
+	return indexed
+}
+
+// hash returns the hash of the file name, or nil if the file doesn't exist,
+// and whether it has been found in the index.
+func (r *indexedReference) hash(name string) ([]byte, bool, error) {
+	if h, ok := r.hashes[name]; ok {
+		return h, true, nil
+	}
+
+	h, err := calcFileHash(r.Reference, name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	r.hashes[name] = h
+	return h, false, nil
+}
+
+// calcFileHash returns the hash of the file name in ref, or nil if the file
+// doesn't exist.
+func calcFileHash(ref Reference, name string) ([]byte, error) {
+	rdr, err := ref.Open(name)
+	if err == ErrFileNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return CalcHash(rdr)
+}
+
+// hashFile returns the hash of the file name in ref, using the index if the
+// reference has been indexed.
+func (b *identify) hashFile(ref Reference, name string) ([]byte, error) {
+	r, ok := ref.(*indexedReference)
+	if !ok {
+		return calcFileHash(ref, name)
+	}
+
+	h, hit, err := r.hash(name)
+	if err != nil {
+		return nil, err
+	} else if hit {
+		b.metrics.inc(metricIndexCacheHits)
+	} else {
+		b.metrics.inc(metricIndexCacheMiss)
+	}
+
+	return h, nil
+}
+
+// unwrapSource returns the underlying source of an indexed source.
+func unwrapSource(s Source) Source {
+	if is, ok := s.(*indexedSource); ok {
+		return is.Source
+	}
+
+	return s
+}
+
+// unwrapReferences returns the underlying references of indexed references.
+func unwrapReferences(refs []Reference) []Reference {
+	unwrapped := make([]Reference, len(refs))
+	for i, ref := range refs {
+		if r, ok := ref.(*indexedReference); ok {
+			unwrapped[i] = r.Reference
+		} else {
+			unwrapped[i] = ref
+		}
+	}
+
+	return unwrapped
+}
+
+// source returns the locked, indexed source of the application, writing its
+// progress to out and downloading with fetch. The returned function releases
+// the source.
+func (i *Index) source(application *Application, cachePath string, fetch FetchFn, out io.Writer) (Source, func(), error) {
+	key := fmt.Sprintf("%s %s %s %v", cachePath, application.Type, application.Repository, application.Archives)
+
+	i.m.Lock()
+	s, ok := i.sources[key]
+	if !ok {
+		w := &indexWriter{w: ioutil.Discard}
+		f := &indexFetcher{}
+
+		source, err := NewSource(application, cachePath, f.fetch, w)
+		if err != nil {
+			i.m.Unlock()
+			return nil, nil, err
+		}
+
+		s = &indexedSource{
+			Source:  source,
+			maxAge:  i.maxAge,
+			out:     w,
+			fetcher: f,
+		}
+
+		i.sources[key] = s
+	}
+	i.m.Unlock()
+
+	s.Lock()
+	s.out.w = out
+	s.fetcher.fn = fetch
+
+	return s, func() {
+		s.out.w = ioutil.Discard
+		s.fetcher.fn = nil
+		s.Unlock()
+	}, nil
+}
+
+// openSource returns the source of the application, shared with other
+// identifications when an index has been set. The returned function releases
+// the source.
+func (b *identify) openSource() (Source, func(), error) {
+	if b.index != nil {
+		return b.index.source(b.application, b.cachePath, b.fetchSource, b.out)
+	}
+
+	source, err := NewSource(b.application, b.cachePath, b.fetchSource, b.out)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return source, func() {}, nil
+}
@@ -0,0 +1,123 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+type testReference struct {
+	name  string
+	files map[string]string
+	opens *int
+}
+
+func (r *testReference) Name() string {
+	return r.name
+}
+
+func (r *testReference) Open(name string) (io.ReadCloser, error) {
+	*r.opens++
+
+	content, ok := r.files[name]
+	if !ok {
+		return nil, ErrFileNotFound
+	}
+
+	return ioutil.NopCloser(strings.NewReader(content)), nil
+}
+
+type testSource struct {
+	updates int
+	tags    []Reference
+}
+
+func (s *testSource) Update() error {
+	s.updates++
+	return nil
+}
+
+func (s *testSource) Branches() ([]Reference, error) {
+	return []Reference{}, nil
+}
+
+func (s *testSource) Tags() ([]Reference, error) {
+	return s.tags, nil
+}
+
+func TestIndexedSource(t *testing.T) {
+	opens := 0
+
+	source := &testSource{
+		tags: []Reference{
+			&testReference{name: "1.0", files: map[string]string{"readme.txt": "1.0"}, opens: &opens},
+		},
+	}
+
+	s := &indexedSource{Source: source, maxAge: 1 << 62}
+
+	for i := 0; i < 2; i++ {
+		if err := s.Update(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if source.updates != 1 {
+		t.Errorf("Expected 1 update, got %d", source.updates)
+	}
+
+	tags, _ := s.Tags()
+
+	m := NewMetrics()
+	b := &identify{metrics: m}
+
+	for i := 0; i < 2; i++ {
+		if h, err := b.hashFile(tags[0], "readme.txt"); err != nil {
+			t.Fatal(err)
+		} else if h == nil {
+			t.Fatal("Expected hash for readme.txt")
+		}
+
+		if h, err := b.hashFile(tags[0], "missing.txt"); err != nil {
+			t.Fatal(err)
+		} else if h != nil {
+			t.Fatal("Expected no hash for missing file")
+		}
+	}
+
+	if opens != 2 {
+		t.Errorf("Expected files to be opened once, got %d opens", opens)
+	}
+
+	buf := &bytes.Buffer{}
+	m.Write(buf)
+
+	if !strings.Contains(buf.String(), "identify_index_cache_hits_total 2\n") {
+		t.Errorf("Expected 2 cache hits in metrics:\n%s", buf.String())
+	}
+
+	if refs := unwrapReferences(tags); refs[0] != source.tags[0] {
+		t.Errorf("Expected unwrapped reference")
+	}
+}
+
+type failingReference struct {
+	testReference
+}
+
+func (r *failingReference) Open(name string) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("Can't read %s", name)
+}
+
+func TestCalcFileHashError(t *testing.T) {
+	// only missing files have no hash, other errors stop the identification
+	b := &identify{metrics: NewMetrics()}
+
+	ref := indexReferences([]Reference{&failingReference{testReference{name: "1.0"}}})[0]
+	if _, err := b.hashFile(ref, "readme.txt"); err == nil {
+		t.Fatal("Expected error reading the file")
+	}
+}
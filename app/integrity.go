@@ -0,0 +1,239 @@
+package app
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cheggaaa/pb"
+	"github.com/fatih/color"
+)
+
+const (
+	DifferenceModified = "modified"
+	DifferenceMissing  = "missing"
+	DifferenceUnknown  = "unknown"
+)
+
+// FileDifference is a file of the target that differs from the identified
+// version.
+type FileDifference struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	Hash   string `json:"hash,omitempty"`
+}
+
+// serverSideExtensions are the extensions of files that are executed or
+// protected by the server, their contents can't be compared over http.
+var serverSideExtensions = map[string]bool{
+	".php":      true,
+	".phtml":    true,
+	".php5":     true,
+	".inc":      true,
+	".asp":      true,
+	".aspx":     true,
+	".jsp":      true,
+	".cgi":      true,
+	".pl":       true,
+	".py":       true,
+	".rb":       true,
+	".htaccess": true,
+	".htpasswd": true,
+}
+
+// walkRoot returns the files of ref under the root of the application,
+// relative to the root.
+func (b *identify) walkRoot(ref Reference) ([]string, error) {
+	w, ok := ref.(Walker)
+	if !ok {
+		return nil, fmt.Errorf("Reference %s can't list its files", ref.Name())
+	}
+
+	root := strings.Trim(b.application.Root, "/")
+
+	files := []string{}
+
+	err := w.Walk(root, func(name string) error {
+		if root != "" {
+			name = strings.TrimPrefix(name, root+"/")
+		}
+
+		files = append(files, name)
+		return nil
+	})
+
+	return files, err
+}
+
+// missingDifferences returns the files of ref that don't exist in the local
+// path.
+func (b *identify) missingDifferences(files []string) []*FileDifference {
+	root := filepath.FromSlash(b.targetURL.Path)
+
+	differences := []*FileDifference{}
+
+	for _, name := range files {
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(name))); os.IsNotExist(err) {
+			differences = append(differences, &FileDifference{Path: name, Status: DifferenceMissing})
+		}
+	}
+
+	return differences
+}
+
+// remoteDifferences fetches the files of ref from the target, and returns
+// the files that are missing or differ from ref. Files executed by the
+// server and files redirected to another path can't be compared and are
+// skipped.
+func (b *identify) remoteDifferences(ref Reference, files []string, fingerprints map[string]*softNotFound) ([]*FileDifference, error) {
+	differences := []*FileDifference{}
+
+	bar := pb.New(len(files))
+	bar.Output = b.out
+	bar.SetWidth(40)
+	bar.SetMaxWidth(40)
+	bar.Format("[## ]")
+	bar.ShowCounters = true
+	bar.ShowFinalTime = false
+	bar.ShowPercent = false
+	bar.Start()
+
+	defer bar.Finish()
+
+	for _, name := range files {
+		bar.Increment()
+
+		if serverSideExtensions[strings.ToLower(path.Ext(name))] {
+			continue
+		}
+
+		abs := b.targetURL.ResolveReference(&url.URL{Path: name})
+
+		resp, err := b.get(abs.String())
+		if err != nil {
+			fmt.Fprintln(b.out, color.RedString("[!] Could not download url %s: %s", name, err.Error()))
+			continue
+		}
+
+		if redirectedElsewhere(resp, abs) {
+			closeBody(resp)
+
+			fmt.Fprintln(b.out, color.YellowString("[!] Can't verify %s, redirected to %s", name, resp.Request.URL.String()))
+			continue
+		} else if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+			closeBody(resp)
+
+			differences = append(differences, &FileDifference{Path: name, Status: DifferenceMissing})
+			continue
+		} else if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+			// forbidden or otherwise not comparable
+			closeBody(resp)
+			continue
+		}
+
+		body, err := readBody(resp, b.maxBodySize)
+		if err != nil {
+			fmt.Fprintln(b.out, color.RedString("[!] Could not download url %s: %s", name, err.Error()))
+			continue
+		}
+
+		if _, ok := isSoftNotFound(fingerprints, name, resp, body); ok {
+			differences = append(differences, &FileDifference{Path: name, Status: DifferenceMissing})
+			continue
+		}
+
+		expected, err := b.hashFile(ref, path.Join(b.application.Root, name))
+		if err != nil {
+			return nil, err
+		}
+
+		hash, err := CalcHash(ioutil.NopCloser(bytes.NewReader(body)))
+		if err != nil {
+			return nil, err
+		}
+
+		if !bytes.Equal(expected, hash) {
+			differences = append(differences, &FileDifference{Path: name, Status: DifferenceModified, Hash: hex.EncodeToString(hash)})
+		}
+	}
+
+	return differences, nil
+}
+
+// compareFiles compares the files of the target with the best matching
+// version of the refs. All local files are hashed to find modified and
+// unknown files, and with an integrity check the files of the version are
+// checked for missing and, for remote targets, modified files.
+func (b *identify) compareFiles(refs []Reference, fingerprints map[string]*softNotFound) error {
+	reports := b.versionReports()
+	if len(reports) == 0 {
+		return nil
+	}
+
+	best := reports[0].Version
+
+	var ref Reference
+	for _, r := range refs {
+		if r.Name() == best {
+			ref = r
+			break
+		}
+	}
+
+	if ref == nil {
+		return nil
+	}
+
+	fmt.Fprintln(b.out, color.YellowString("[+] Comparing files with version %s", best))
+
+	differences := []*FileDifference{}
+
+	if isLocal(b.targetURL) {
+		if d, err := b.localDifferences(ref); err != nil {
+			return err
+		} else {
+			differences = append(differences, d...)
+		}
+	}
+
+	if b.integrity {
+		files, err := b.walkRoot(ref)
+		if err != nil {
+			return err
+		}
+
+		if isLocal(b.targetURL) {
+			differences = append(differences, b.missingDifferences(files)...)
+		} else if d, err := b.remoteDifferences(ref, files, fingerprints); err != nil {
+			return err
+		} else {
+			differences = append(differences, d...)
+		}
+	}
+
+	sort.Slice(differences, func(i, j int) bool {
+		return differences[i].Path < differences[j].Path
+	})
+
+	counts := map[string]int{}
+
+	for _, difference := range differences {
+		counts[difference.Status]++
+
+		fmt.Fprintln(b.out, color.RedString(" |  %-8s %s", difference.Status, difference.Path))
+	}
+
+	fmt.Fprintln(b.out, color.YellowString("[+] %d files differ from version %s: %d modified, %d missing, %d unknown", len(differences), best, counts[DifferenceModified], counts[DifferenceMissing], counts[DifferenceUnknown]))
+	fmt.Fprintln(b.out)
+
+	b.differences = differences
+	return nil
+}
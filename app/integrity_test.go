@@ -0,0 +1,106 @@
+package app
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIntegrity(t *testing.T) {
+	dir, err := ioutil.TempDir("", "identify")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	archives := filepath.Join(dir, "archives")
+	os.Mkdir(archives, 0700)
+
+	writeZip(t, filepath.Join(archives, "application-1.0.0.zip"), map[string]string{
+		"application-1.0.0/public/readme.txt":    "1.0.0",
+		"application-1.0.0/public/css/style.css": "body {}",
+		"application-1.0.0/public/js/app.js":     "app()",
+		"application-1.0.0/public/css/print.css": "@media print {}",
+		"application-1.0.0/public/index.php":     "<?php",
+		"application-1.0.0/tests/test.php":       "<?php",
+	})
+
+	docroot := filepath.Join(dir, "docroot")
+	writeFiles(t, docroot, map[string]string{
+		"readme.txt":    "1.0.0",
+		"css/style.css": "body { display: none }",
+		"index.php":     "<?php",
+	})
+
+	// a file that redirects to another page can't be verified
+	fs := http.FileServer(http.Dir(docroot))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/css/print.css" {
+			http.Redirect(w, r, "/readme.txt", http.StatusFound)
+			return
+		}
+
+		fs.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	tests := map[string]map[string]string{
+		"file://" + filepath.ToSlash(docroot) + "/": {
+			"css/style.css": DifferenceModified,
+			"css/print.css": DifferenceMissing,
+			"js/app.js":     DifferenceMissing,
+		},
+		server.URL + "/": {
+			"css/style.css": DifferenceModified,
+			"js/app.js":     DifferenceMissing,
+		},
+	}
+
+	for target, expected := range tests {
+		b := newTestIdentify(t, dir, &Application{
+			Name:       "application",
+			Type:       SourceArchive,
+			Repository: archives,
+			Root:       "public",
+			Files:      []string{"readme.txt"},
+		})
+
+		b.integrity = true
+
+		if u, _ := url.Parse(target); isLocal(u) {
+			fn, err := LocalPath(u.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			fn(b)
+		} else {
+			b.targetURL = u
+		}
+
+		if err := b.identify(); err != nil {
+			t.Fatal(err)
+		}
+
+		differences := map[string]string{}
+		for _, difference := range b.Report().Differences {
+			differences[difference.Path] = difference.Status
+		}
+
+		if len(differences) != len(expected) {
+			t.Errorf("%s: unexpected differences: %v", target, differences)
+			continue
+		}
+
+		for name, status := range expected {
+			if differences[name] != status {
+				t.Errorf("%s: expected %s to be %s, got %s", target, name, status, differences[name])
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package app
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// newResponse returns a response to req, as if it has been returned by a
+// server.
+func newResponse(req *http.Request, code int, header http.Header, body io.ReadCloser, size int64) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+
+	if body == nil {
+		body = ioutil.NopCloser(bytes.NewReader(nil))
+	}
+
+	return &http.Response{
+		Status:        strconv.Itoa(code) + " " + http.StatusText(code),
+		StatusCode:    code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          body,
+		ContentLength: size,
+		Request:       req,
+	}
+}
+
+// fileTransport serves file urls from the local filesystem, confined to the
+// directory root. Files that don't exist are returned as 404.
+type fileTransport struct {
+	root string
+}
+
+func (t *fileTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "file" {
+		return nil, fmt.Errorf("Unsupported scheme for local path: %s", req.URL.Scheme)
+	}
+
+	name := filepath.FromSlash(path.Clean(req.URL.Path))
+
+	if rel, err := filepath.Rel(t.root, name); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return newResponse(req, http.StatusNotFound, nil, nil, 0), nil
+	}
+
+	fi, err := os.Stat(name)
+	if os.IsNotExist(err) {
+		return newResponse(req, http.StatusNotFound, nil, nil, 0), nil
+	} else if err != nil {
+		return nil, err
+	} else if !fi.Mode().IsRegular() {
+		return newResponse(req, http.StatusNotFound, nil, nil, 0), nil
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	if contentType := mime.TypeByExtension(filepath.Ext(name)); contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+
+	return newResponse(req, http.StatusOK, header, f, fi.Size()), nil
+}
+
+// localDifferences hashes all files in the directory of the target url, and
+// returns the files that differ from ref.
+func (b *identify) localDifferences(ref Reference) ([]*FileDifference, error) {
+	root := filepath.FromSlash(b.targetURL.Path)
+
+	differences := []*FileDifference{}
+
+	err := filepath.Walk(root, func(name string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		} else if !fi.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+
+		rel = filepath.ToSlash(rel)
+
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+
+		hash, err := CalcHash(f)
+		if err != nil {
+			return err
+		}
+
+		expected, err := b.hashFile(ref, path.Join(b.application.Root, rel))
+		if err != nil {
+			return err
+		}
+
+		if expected == nil {
+			differences = append(differences, &FileDifference{Path: rel, Status: DifferenceUnknown, Hash: hex.EncodeToString(hash)})
+		} else if !bytes.Equal(expected, hash) {
+			differences = append(differences, &FileDifference{Path: rel, Status: DifferenceModified, Hash: hex.EncodeToString(hash)})
+		}
+
+		return nil
+	})
+
+	return differences, err
+}
+
+// isLocal returns true if the target is a local path.
+func isLocal(u *url.URL) bool {
+	return u.Scheme == "file"
+}
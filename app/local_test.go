@@ -0,0 +1,185 @@
+package app
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		name = filepath.Join(root, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(name), 0700); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(name, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func writeZip(t *testing.T, name string, files map[string]string) {
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w.Write([]byte(content))
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTestIdentify returns an identification of application without output,
+// caching in dir.
+func newTestIdentify(t *testing.T, dir string, application *Application) *identify {
+	t.Helper()
+
+	b := &identify{
+		client:      &http.Client{},
+		hashes:      map[string]*Result{},
+		versions:    []string{},
+		cachePath:   filepath.Join(dir, "cache"),
+		out:         ioutil.Discard,
+		application: application,
+	}
+
+	b.maxBodySize = defaultMaxBodySize
+	return b
+}
+
+func TestLocalPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "identify")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	archives := filepath.Join(dir, "archives")
+	os.Mkdir(archives, 0700)
+
+	writeZip(t, filepath.Join(archives, "application-1.0.0.zip"), map[string]string{
+		"application-1.0.0/readme.txt":    "1.0.0",
+		"application-1.0.0/css/style.css": "body {}",
+		"application-1.0.0/index.php":     "<?php",
+	})
+
+	writeZip(t, filepath.Join(archives, "application-1.1.0.zip"), map[string]string{
+		"application-1.1.0/readme.txt":    "1.1.0",
+		"application-1.1.0/css/style.css": "body {}",
+		"application-1.1.0/index.php":     "<?php",
+	})
+
+	docroot := filepath.Join(dir, "docroot")
+	writeFiles(t, docroot, map[string]string{
+		"readme.txt":    "1.1.0",
+		"css/style.css": "body {}",
+		"index.php":     "<?php // backdoor",
+		"config.php":    "<?php",
+	})
+
+	b := newTestIdentify(t, dir, &Application{
+		Name:       "application",
+		Type:       SourceArchive,
+		Repository: archives,
+		Files:      []string{"readme.txt", "css/style.css", "missing.js"},
+	})
+
+	b.allFiles = true
+
+	fn, err := LocalPath(docroot)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := fn(b); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.identify(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := b.Report()
+	if best := r.Best(); best == nil || best.Version != "1.1.0" {
+		t.Fatalf("Expected version 1.1.0, got %#v", best)
+	}
+
+	differences := map[string]string{}
+	for _, difference := range r.Differences {
+		differences[difference.Path] = difference.Status
+	}
+
+	if len(differences) != 2 || differences["index.php"] != DifferenceModified || differences["config.php"] != DifferenceUnknown {
+		t.Errorf("Unexpected differences: %v", differences)
+	}
+
+	resp, err := b.client.Get("file://" + filepath.ToSlash(dir) + "/archives/application-1.0.0.zip")
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected files outside of the local path to be not found, got %d", resp.StatusCode)
+	}
+}
+
+func TestLocalPathProxy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "identify")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"readme.txt": "1.0.0",
+	})
+
+	local, err := LocalPath(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proxy, err := ProxyURL("socks5://127.0.0.1:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the proxy applies to the network, in either order
+	for _, options := range [][]OptionFn{{local, proxy}, {proxy, local}} {
+		b := newTestIdentify(t, dir, nil)
+
+		for _, fn := range options {
+			if err := fn(b); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		resp, err := b.client.Get(b.targetURL.String() + "readme.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected local file to be read, got %d", resp.StatusCode)
+		} else if b.transport == nil {
+			t.Errorf("Expected proxy transport for the sources")
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	metricTargetsScanned = "identify_targets_scanned_total"
+	metricFetchDuration  = "identify_fetch_duration_seconds"
+	metricFetchErrors    = "identify_fetch_errors_total"
+	metricResponses      = "identify_http_responses_total"
+	metricSourceUpdate   = "identify_source_update_duration_seconds"
+	metricIndexBuild     = "identify_index_build_duration_seconds"
+	metricIndexCacheHits = "identify_index_cache_hits_total"
+	metricIndexCacheMiss = "identify_index_cache_misses_total"
+)
+
+const (
+	metricTypeCounter   = "counter"
+	metricTypeHistogram = "histogram"
+)
+
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var (
+	fetchBuckets    = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30}
+	durationBuckets = []float64{.1, .5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600}
+)
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+type metric struct {
+	name    string
+	help    string
+	typ     string
+	buckets []float64
+
+	counters   map[string]float64
+	histograms map[string]*histogram
+}
+
+// Metrics collects metrics of identifications, and writes them in the
+// Prometheus text exposition format. A nil Metrics discards all metrics.
+type Metrics struct {
+	m       sync.Mutex
+	metrics map[string]*metric
+}
+
+// NewMetrics returns metrics with all metrics of identify registered.
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		metrics: map[string]*metric{},
+	}
+
+	m.register(metricTargetsScanned, metricTypeCounter, "Number of targets scanned, by application and result.", nil)
+	m.register(metricFetchDuration, metricTypeHistogram, "Duration of requests to targets in seconds.", fetchBuckets)
+	m.register(metricFetchErrors, metricTypeCounter, "Number of requests to targets that failed.", nil)
+	m.register(metricResponses, metricTypeCounter, "Number of responses of targets, by status code.", nil)
+	m.register(metricSourceUpdate, metricTypeHistogram, "Duration of updating the source of an application in seconds, by source type.", durationBuckets)
+	m.register(metricIndexBuild, metricTypeHistogram, "Duration of hashing the files of all versions in seconds, by application.", durationBuckets)
+	m.register(metricIndexCacheHits, metricTypeCounter, "Number of file hashes found in the index.", nil)
+	m.register(metricIndexCacheMiss, metricTypeCounter, "Number of file hashes not found in the index.", nil)
+
+	return m
+}
+
+func (m *Metrics) register(name string, typ string, help string, buckets []float64) {
+	m.metrics[name] = &metric{
+		name:       name,
+		help:       help,
+		typ:        typ,
+		buckets:    buckets,
+		counters:   map[string]float64{},
+		histograms: map[string]*histogram{},
+	}
+}
+
+// formatLabels formats label pairs like {code="200"}.
+func formatLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+	parts := []string{}
+	for i := 0; i+1 < len(labels); i += 2 {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, labels[i], r.Replace(labels[i+1])))
+	}
+
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// inc increments the counter name with the label pairs.
+func (m *Metrics) inc(name string, labels ...string) {
+	if m == nil {
+		return
+	}
+
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	m.metrics[name].counters[formatLabels(labels)]++
+}
+
+// observe adds the value v to the histogram name with the label pairs.
+func (m *Metrics) observe(name string, v float64, labels ...string) {
+	if m == nil {
+		return
+	}
+
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	metric := m.metrics[name]
+
+	key := formatLabels(labels)
+
+	h, ok := metric.histograms[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(metric.buckets))}
+		metric.histograms[key] = h
+	}
+
+	for i, le := range metric.buckets {
+		if v <= le {
+			h.counts[i]++
+		}
+	}
+
+	h.sum += v
+	h.count++
+}
+
+// withLabel adds the label to the formatted labels.
+func withLabel(labels string, label string) string {
+	if labels == "" {
+		return "{" + label + "}"
+	}
+
+	return labels[:len(labels)-1] + "," + label + "}"
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// Write writes the metrics in the Prometheus text exposition format to w.
+func (m *Metrics) Write(w io.Writer) error {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	names := []string{}
+	for name := range m.metrics {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		metric := m.metrics[name]
+
+		fmt.Fprintf(w, "# HELP %s %s\n", name, metric.help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", name, metric.typ)
+
+		if metric.typ == metricTypeCounter {
+			keys := []string{}
+			for key := range metric.counters {
+				keys = append(keys, key)
+			}
+
+			sort.Strings(keys)
+
+			for _, key := range keys {
+				fmt.Fprintf(w, "%s%s %s\n", name, key, formatFloat(metric.counters[key]))
+			}
+
+			continue
+		}
+
+		keys := []string{}
+		for key := range metric.histograms {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			h := metric.histograms[key]
+
+			for i, le := range metric.buckets {
+				fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(key, `le="`+formatFloat(le)+`"`), h.counts[i])
+			}
+
+			fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(key, `le="+Inf"`), h.count)
+			fmt.Fprintf(w, "%s_sum%s %s\n", name, key, formatFloat(h.sum))
+			fmt.Fprintf(w, "%s_count%s %d\n", name, key, h.count)
+		}
+	}
+
+	return nil
+}
+
+// ServeHTTP serves the metrics in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", metricsContentType)
+	m.Write(w)
+}
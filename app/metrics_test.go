@@ -0,0 +1,42 @@
+package app
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	m := NewMetrics()
+
+	m.inc(metricResponses, "code", "200")
+	m.inc(metricResponses, "code", "200")
+	m.inc(metricResponses, "code", "404")
+	m.observe(metricFetchDuration, 0.02)
+	m.observe(metricFetchDuration, 3)
+	m.observe(metricSourceUpdate, 1, "type", `g"it`)
+
+	buf := &bytes.Buffer{}
+	if err := m.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{
+		"# TYPE identify_http_responses_total counter",
+		`identify_http_responses_total{code="200"} 2`,
+		`identify_http_responses_total{code="404"} 1`,
+		`identify_fetch_duration_seconds_bucket{le="0.025"} 1`,
+		`identify_fetch_duration_seconds_bucket{le="5"} 2`,
+		`identify_fetch_duration_seconds_bucket{le="+Inf"} 2`,
+		"identify_fetch_duration_seconds_sum 3.02",
+		"identify_fetch_duration_seconds_count 2",
+		`identify_source_update_duration_seconds_bucket{type="g\"it",le="1"} 1`,
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("Expected line %s in:\n%s", line, buf.String())
+		}
+	}
+
+	var nilMetrics *Metrics
+	nilMetrics.inc(metricResponses, "code", "200")
+}
@@ -0,0 +1,281 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	version "github.com/hashicorp/go-version"
+)
+
+const (
+	EventVersionChanged   = "version-changed"
+	EventVersionRegressed = "version-regressed"
+	EventInconsistent     = "inconsistent-evidence"
+)
+
+// defaultTick is the interval at which the monitor looks for targets due.
+const defaultTick = time.Minute
+
+// Scan is the result of a single scan of a monitored target.
+type Scan struct {
+	Time        time.Time `json:"time"`
+	Application string    `json:"application"`
+	Version     string    `json:"version,omitempty"`
+	Candidates  []string  `json:"candidates,omitempty"`
+	Score       float64   `json:"score"`
+
+	// Consistent is true if all fetched files match the identified version
+	Consistent bool `json:"consistent"`
+
+	Error  string  `json:"error,omitempty"`
+	Report *Report `json:"report,omitempty"`
+}
+
+// NewScan summarizes the report of a scan, or the error of a failed scan.
+func NewScan(report *Report, err error) *Scan {
+	scan := &Scan{
+		Time: time.Now(),
+	}
+
+	if err != nil {
+		scan.Error = err.Error()
+		return scan
+	}
+
+	scan.Application = report.Application
+	scan.Report = report
+
+	best := report.Best()
+	if best == nil {
+		return scan
+	}
+
+	scan.Version = best.Version
+	scan.Score = best.Score
+	scan.Consistent = best.Matches == report.hashedFiles()
+
+	for _, vr := range report.Versions {
+		if vr.Matches != best.Matches {
+			break
+		}
+
+		scan.Candidates = append(scan.Candidates, vr.Version)
+	}
+
+	return scan
+}
+
+// Event is raised when a scan differs from the previous scan of a target.
+type Event struct {
+	Type        string    `json:"type"`
+	Time        time.Time `json:"time"`
+	URL         string    `json:"url"`
+	Application string    `json:"application"`
+	Previous    string    `json:"previous,omitempty"`
+	Current     string    `json:"current,omitempty"`
+	Message     string    `json:"message"`
+}
+
+// older returns true if version a is older than version b.
+func older(a string, b string) bool {
+	va, err := version.NewVersion(a)
+	if err != nil {
+		return false
+	}
+
+	vb, err := version.NewVersion(b)
+	if err != nil {
+		return false
+	}
+
+	return va.LessThan(vb)
+}
+
+// contains returns true if the versions contain v.
+func contains(versions []string, v string) bool {
+	for _, s := range versions {
+		if s == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CompareScans returns the events of the current scan of the target with url,
+// compared to the previous identified scan, which may be nil.
+func CompareScans(url string, previous *Scan, current *Scan) []*Event {
+	events := []*Event{}
+
+	if current.Version == "" {
+		return events
+	}
+
+	newEvent := func(typ string, format string, args ...interface{}) *Event {
+		event := &Event{
+			Type:        typ,
+			Time:        current.Time,
+			URL:         url,
+			Application: current.Application,
+			Current:     current.Version,
+			Message:     fmt.Sprintf(format, args...),
+		}
+
+		if previous != nil {
+			event.Previous = previous.Version
+		}
+
+		return event
+	}
+
+	// a change of the best candidate within the same candidates isn't a change
+	if previous == nil {
+	} else if previous.Version == current.Version || contains(current.Candidates, previous.Version) {
+	} else if older(current.Version, previous.Version) {
+		events = append(events, newEvent(EventVersionRegressed, "%s regressed from %s to %s", url, previous.Version, current.Version))
+	} else {
+		events = append(events, newEvent(EventVersionChanged, "%s changed from %s to %s", url, previous.Version, current.Version))
+	}
+
+	if current.Consistent {
+	} else if previous != nil && !previous.Consistent {
+	} else {
+		events = append(events, newEvent(EventInconsistent, "%s serves files that don't all match version %s", url, current.Version))
+	}
+
+	return events
+}
+
+// Monitor scans the targets of the store when they are due, and raises events
+// when the identified version changes.
+type Monitor struct {
+	store   *Store
+	options []OptionFn
+	out     io.Writer
+	tick    time.Duration
+
+	handlers []func(*Event, *Report)
+}
+
+// NewMonitor returns a monitor scanning with the options, writing its
+// progress to out.
+func NewMonitor(store *Store, out io.Writer, options ...OptionFn) *Monitor {
+	return &Monitor{
+		store:   store,
+		options: options,
+		out:     out,
+		tick:    defaultTick,
+	}
+}
+
+// OnEvent adds a handler that is called for every event raised, with the
+// report of the scan that raised it.
+func (m *Monitor) OnEvent(fn func(*Event, *Report)) {
+	m.handlers = append(m.handlers, fn)
+}
+
+// Run scans the targets that are due until the context is done.
+func (m *Monitor) Run(ctx context.Context) error {
+	for {
+		targets, err := m.store.Targets()
+		if err != nil {
+			return err
+		}
+
+		for _, target := range targets {
+			if ctx.Err() != nil {
+				return nil
+			} else if !target.Due(time.Now()) {
+				continue
+			}
+
+			if _, err := m.Scan(target); err != nil {
+				fmt.Fprintln(m.out, color.RedString("[!] Error scanning %s: %s", target.URL, err.Error()))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(m.tick):
+		}
+	}
+}
+
+// previousScan returns the last identified scan of the target.
+func (m *Monitor) previousScan(url string) (*Scan, error) {
+	return m.store.LastIdentifiedScan(url)
+}
+
+func (m *Monitor) identify(target *Target) (*Report, error) {
+	options := append([]OptionFn{}, m.options...)
+
+	if fn, err := TargetURL(target.URL); err != nil {
+		return nil, err
+	} else {
+		options = append(options, fn)
+	}
+
+	if fn, err := TargetApplication(target.Application); err != nil {
+		return nil, err
+	} else {
+		options = append(options, fn)
+	}
+
+	b, err := New(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := b.Identify(); err != nil {
+		return nil, err
+	}
+
+	return b.Report(), nil
+}
+
+// Scan scans the target, stores the scan and raises the events.
+func (m *Monitor) Scan(target *Target) (*Scan, error) {
+	previous, err := m.previousScan(target.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	scan := NewScan(m.identify(target))
+
+	if scan.Error != "" {
+		fmt.Fprintln(m.out, color.RedString("[!] %s: %s", target.URL, scan.Error))
+	} else if scan.Version == "" {
+		fmt.Fprintln(m.out, color.YellowString("[+] %s: could not identify %s", target.URL, target.Application))
+	} else {
+		fmt.Fprintln(m.out, color.GreenString("[+] %s: %s %s (%.0f%%)", target.URL, scan.Application, strings.Join(scan.Candidates, ", "), scan.Score*100))
+	}
+
+	if err := m.store.AddScan(target.URL, scan); err != nil {
+		return nil, err
+	}
+
+	target.LastScan = scan.Time
+
+	if err := m.store.SetLastScan(target.URL, scan.Time); err != nil {
+		return nil, err
+	}
+
+	for _, event := range CompareScans(target.URL, previous, scan) {
+		fmt.Fprintln(m.out, color.RedString("[!] %s", event.Message))
+
+		if err := m.store.AddEvent(event); err != nil {
+			return nil, err
+		}
+
+		for _, fn := range m.handlers {
+			fn(event, scan.Report)
+		}
+	}
+
+	return scan, nil
+}
@@ -0,0 +1,113 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCompareScans(t *testing.T) {
+	previous := &Scan{Version: "4.7.1", Candidates: []string{"4.7.1"}, Consistent: true}
+
+	tests := []struct {
+		current *Scan
+		events  []string
+	}{
+		{&Scan{Version: "4.7.1", Candidates: []string{"4.7.1"}, Consistent: true}, []string{}},
+		{&Scan{Version: "4.7.2", Candidates: []string{"4.7.2", "4.7.1"}, Consistent: true}, []string{}},
+		{&Scan{Version: "4.7.2", Candidates: []string{"4.7.2"}, Consistent: true}, []string{EventVersionChanged}},
+		{&Scan{Version: "4.6.0", Candidates: []string{"4.6.0"}, Consistent: true}, []string{EventVersionRegressed}},
+		{&Scan{Version: "4.7.1", Candidates: []string{"4.7.1"}, Consistent: false}, []string{EventInconsistent}},
+		{&Scan{Error: "timeout"}, []string{}},
+	}
+
+	for i, test := range tests {
+		events := CompareScans("https://example.com/", previous, test.current)
+		if len(events) != len(test.events) {
+			t.Errorf("Test %d: expected %d events, got %d", i, len(test.events), len(events))
+			continue
+		}
+
+		for j, event := range events {
+			if event.Type != test.events[j] {
+				t.Errorf("Test %d: expected event %s, got %s", i, test.events[j], event.Type)
+			}
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "identify")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	s, err := OpenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	url := "https://example.com/"
+
+	if err := s.SaveTarget(&Target{URL: url, Application: "wordpress", Interval: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+
+	if err := s.SetLastScan(url, now); err != nil {
+		t.Fatal(err)
+	}
+
+	targets, err := s.Targets()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(targets) != 1 {
+		t.Fatalf("Expected 1 target, got %d", len(targets))
+	} else if targets[0].Due(now) || !targets[0].Due(now.Add(time.Hour)) {
+		t.Errorf("Unexpected due of target scanned at %s", targets[0].LastScan)
+	}
+
+	if scan, err := s.LastIdentifiedScan(url); err != nil {
+		t.Fatal(err)
+	} else if scan != nil {
+		t.Errorf("Expected no identified scan, got %#v", scan)
+	}
+
+	for _, v := range []string{"4.7.0", "4.7.1", ""} {
+		if err := s.AddScan(url, &Scan{Version: v}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if scans, err := s.Scans(url); err != nil {
+		t.Fatal(err)
+	} else if len(scans) != 3 || scans[1].Version != "4.7.1" {
+		t.Errorf("Unexpected scans: %#v", scans)
+	}
+
+	if scan, err := s.LastIdentifiedScan(url); err != nil {
+		t.Fatal(err)
+	} else if scan == nil || scan.Version != "4.7.1" {
+		t.Errorf("Unexpected last identified scan: %#v", scan)
+	}
+
+	// histories without the last identified scan
+	os.Remove(filepath.Join(s.targetPath(url), "identified.json"))
+
+	if scan, err := s.LastIdentifiedScan(url); err != nil {
+		t.Fatal(err)
+	} else if scan == nil || scan.Version != "4.7.1" {
+		t.Errorf("Unexpected last identified scan from history: %#v", scan)
+	}
+
+	if err := s.RemoveTarget(url); err != nil {
+		t.Fatal(err)
+	} else if err := s.RemoveTarget(url); err == nil {
+		t.Errorf("Expected error removing unknown target")
+	}
+}
@@ -1,9 +1,15 @@
 package app
 
 import (
+	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"golang.org/x/net/proxy"
 )
@@ -11,8 +17,40 @@ import (
 type config struct {
 	noBranches bool
 	noTags     bool
+	noSoft404  bool
 	debug      bool
 
+	redirectPolicy string
+	maxRedirects   int
+
+	overallTimeout time.Duration
+	retries        int
+	backoff        time.Duration
+	maxBodySize    int64
+
+	commits    bool
+	maxCommits int
+
+	image    string
+	capture  string
+	evidence string
+	bundle   string
+
+	// probes are the names of the non-existent files requested per
+	// extension to fingerprint not found pages
+	probes map[string]string
+
+	// toolVersion is the version of identify, recorded in reports
+	toolVersion string
+
+	allFiles  bool
+	integrity bool
+
+	scanComponents bool
+	slugs          []string
+
+	advisories Advisories
+
 	targetApplication string
 	targetURL         *url.URL
 }
@@ -40,6 +78,132 @@ func NoBranches() (func(b *identify) error, error) {
 	}, nil
 }
 
+func NoSoft404() (func(b *identify) error, error) {
+	return func(b *identify) error {
+		b.noSoft404 = true
+		return nil
+	}, nil
+}
+
+func RedirectPolicy(s string) (func(b *identify) error, error) {
+	switch s {
+	case RedirectFollow, RedirectSameHost, RedirectNever:
+	default:
+		return nil, fmt.Errorf("Unknown redirect policy: %s", s)
+	}
+
+	return func(b *identify) error {
+		b.redirectPolicy = s
+		return nil
+	}, nil
+}
+
+func MaxRedirects(n int) (func(b *identify) error, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("Invalid number of redirects: %d", n)
+	}
+
+	return func(b *identify) error {
+		b.maxRedirects = n
+		return nil
+	}, nil
+}
+
+func Timeout(d time.Duration) (func(b *identify) error, error) {
+	return func(b *identify) error {
+		b.client.Timeout = d
+		return nil
+	}, nil
+}
+
+func OverallTimeout(d time.Duration) (func(b *identify) error, error) {
+	return func(b *identify) error {
+		b.overallTimeout = d
+		return nil
+	}, nil
+}
+
+func Retries(n int) (func(b *identify) error, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("Invalid number of retries: %d", n)
+	}
+
+	return func(b *identify) error {
+		b.retries = n
+		return nil
+	}, nil
+}
+
+func Backoff(d time.Duration) (func(b *identify) error, error) {
+	if d < 0 {
+		return nil, fmt.Errorf("Invalid backoff: %s", d)
+	}
+
+	return func(b *identify) error {
+		b.backoff = d
+		return nil
+	}, nil
+}
+
+func MaxBodySize(n int64) (func(b *identify) error, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("Invalid maximum body size: %d", n)
+	}
+
+	return func(b *identify) error {
+		b.maxBodySize = n
+		return nil
+	}, nil
+}
+
+func Commits() (func(b *identify) error, error) {
+	return func(b *identify) error {
+		b.commits = true
+		return nil
+	}, nil
+}
+
+func MaxCommits(n int) (func(b *identify) error, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("Invalid maximum number of commits: %d", n)
+	}
+
+	return func(b *identify) error {
+		b.maxCommits = n
+		return nil
+	}, nil
+}
+
+// Components enables identification of the components of the application,
+// using slugs instead of the slugs of the rule set when not empty. Slugs are
+// scoped with the kind of component, like plugin:akismet, which can be left
+// out when the application has a single kind of components.
+func Components(slugs []string) (func(b *identify) error, error) {
+	for _, slug := range slugs {
+		if _, _, err := parseSlug(slug); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(b *identify) error {
+		b.scanComponents = true
+		b.slugs = slugs
+		return nil
+	}, nil
+}
+
+func AdvisoriesPath(s string) (func(b *identify) error, error) {
+	advisories, err := LoadAdvisories(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(b *identify) error {
+		b.advisories = advisories
+		return nil
+	}, nil
+}
+
 func ProxyURL(s string) (func(b *identify) error, error) {
 	dialer := net.Dial
 
@@ -52,14 +216,20 @@ func ProxyURL(s string) (func(b *identify) error, error) {
 		return nil, err
 	} else {
 		dialer = v.Dial
+		proxyURL = u
 	}
 
 	return func(b *identify) error {
-		b.client.Transport = &http.Transport{
+		b.transport = &http.Transport{
 			Proxy: http.ProxyFromEnvironment,
 			Dial:  dialer,
 		}
 
+		// local paths and captures are read without the network
+		if _, ok := b.client.Transport.(*http.Transport); ok || b.client.Transport == nil {
+			b.client.Transport = b.transport
+		}
+
 		b.proxyURL = proxyURL
 
 		return nil
@@ -73,6 +243,31 @@ func CachePath(s string) (func(b *identify) error, error) {
 	}, nil
 }
 
+// SharedIndex shares the sources and hashes of the index with other
+// identifications using it.
+func SharedIndex(index *Index) (func(b *identify) error, error) {
+	return func(b *identify) error {
+		b.index = index
+		return nil
+	}, nil
+}
+
+// CollectMetrics collects the metrics of the identification in m.
+func CollectMetrics(m *Metrics) (func(b *identify) error, error) {
+	return func(b *identify) error {
+		b.metrics = m
+		return nil
+	}, nil
+}
+
+// Notify notifies the hooks of the events of the identification.
+func Notify(h *Hooks) (func(b *identify) error, error) {
+	return func(b *identify) error {
+		b.hooks = h
+		return nil
+	}, nil
+}
+
 func UserAgent(s string) (func(b *identify) error, error) {
 	return func(b *identify) error {
 		// todo
@@ -80,6 +275,14 @@ func UserAgent(s string) (func(b *identify) error, error) {
 	}, nil
 }
 
+// Output sets the writer for the console output, defaults to stdout.
+func Output(w io.Writer) (func(b *identify) error, error) {
+	return func(b *identify) error {
+		b.out = w
+		return nil
+	}, nil
+}
+
 func TargetApplication(s string) (func(b *identify) error, error) {
 	return func(b *identify) error {
 		b.targetApplication = s
@@ -87,12 +290,132 @@ func TargetApplication(s string) (func(b *identify) error, error) {
 	}, nil
 }
 
+// LocalPath identifies the application installed in the directory root,
+// reading the files from disk instead of requesting them.
+func LocalPath(root string) (func(b *identify) error, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	if fi, err := os.Stat(abs); err != nil {
+		return nil, err
+	} else if !fi.IsDir() {
+		return nil, fmt.Errorf("Local path is not a directory: %s", root)
+	}
+
+	u := &url.URL{
+		Scheme: "file",
+		Path:   strings.TrimSuffix(filepath.ToSlash(abs), "/") + "/",
+	}
+
+	return func(b *identify) error {
+		b.targetURL = u
+		b.client.Transport = &fileTransport{root: abs}
+		return nil
+	}, nil
+}
+
+// StoreEvidence stores the raw responses of the identification in the
+// evidence directory path, per target, so they can be analyzed again with
+// Capture.
+func StoreEvidence(path string) (func(b *identify) error, error) {
+	return func(b *identify) error {
+		b.evidence = path
+		return nil
+	}, nil
+}
+
+// Bundle writes an evidence bundle of the identification to filename, with
+// the responses, their checksums, the report and the database and
+// repository state that produced it.
+func Bundle(filename string) (func(b *identify) error, error) {
+	return func(b *identify) error {
+		b.bundle = filename
+		return nil
+	}, nil
+}
+
+// NotFoundProbes sets the names of the non-existent files requested per
+// extension to fingerprint not found pages, as stored with the evidence, so
+// the stored probes are requested again when reanalyzing.
+func NotFoundProbes(probes map[string]string) (func(b *identify) error, error) {
+	return func(b *identify) error {
+		b.probes = map[string]string{}
+		for ext, name := range probes {
+			b.probes[ext] = name
+		}
+
+		return nil
+	}, nil
+}
+
+// Capture identifies the application passively from captured traffic,
+// without any network access. Location is a HAR file, a WARC file or a
+// directory mirrored with wget, the target url is used as the base url of
+// the application in the capture.
+func Capture(location string) (func(b *identify) error, error) {
+	t, err := openCapture(location)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(b *identify) error {
+		b.client.Transport = t
+		b.capture = location
+		return nil
+	}, nil
+}
+
+// ToolVersion sets the version of identify, as recorded in reports.
+func ToolVersion(v string) (func(b *identify) error, error) {
+	return func(b *identify) error {
+		b.toolVersion = v
+		return nil
+	}, nil
+}
+
+// Image identifies the application in the container image at location, a
+// docker save tarball or an OCI layout directory.
+func Image(location string) (func(b *identify) error, error) {
+	if _, err := os.Stat(location); err != nil {
+		return nil, err
+	}
+
+	return func(b *identify) error {
+		b.image = location
+		return nil
+	}, nil
+}
+
+// AllFiles compares all files of a local path with the identified version.
+func AllFiles() (func(b *identify) error, error) {
+	return func(b *identify) error {
+		b.allFiles = true
+		return nil
+	}, nil
+}
+
+// Integrity compares the files of the identified version with the target,
+// reporting modified, missing and unknown files.
+func Integrity() (func(b *identify) error, error) {
+	return func(b *identify) error {
+		b.integrity = true
+		return nil
+	}, nil
+}
+
+// TargetURL sets the url to identify, file urls identify a local path.
 func TargetURL(s string) (func(b *identify) error, error) {
 	u, err := url.Parse(s)
 	if err != nil {
 		return nil, err
 	}
 
+	if isLocal(u) {
+		return LocalPath(u.Path)
+	}
+
 	return func(b *identify) error {
 		b.targetURL = u
 		return nil
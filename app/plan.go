@@ -0,0 +1,72 @@
+package app
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// planFiles returns the urls of the files of the application, relative to
+// base, and of the non-existent files requested to fingerprint not found
+// pages, as requested when ingesting.
+func (b *identify) planFiles(application *Application, base *url.URL) ([]string, error) {
+	files := append([]string{}, application.Files...)
+
+	if !b.noSoft404 {
+		for _, file := range probeFiles(application.Files) {
+			files = append(files, probePath(file, capturedProbeName))
+		}
+	}
+
+	urls := []string{}
+
+	for _, file := range files {
+		rel, err := url.Parse(file)
+		if err != nil {
+			return nil, fmt.Errorf("Could not parse url %s: %s", file, err.Error())
+		}
+
+		urls = append(urls, base.ResolveReference(rel).String())
+	}
+
+	return urls, nil
+}
+
+// Plan returns the urls that will be requested to identify the application,
+// without requesting them: the target url, the files of the application and
+// the files of its components when they are identified. The responses can be
+// fetched by any tool and identified with Capture. Requests that depend on
+// responses, like the integrity check, aren't part of the plan.
+func (b *identify) Plan() ([]string, error) {
+	if b.application == nil {
+		return nil, fmt.Errorf("Planning requires an application, auto detection depends on the responses")
+	}
+
+	urls := []string{b.targetURL.String()}
+
+	files, err := b.planFiles(b.application, b.targetURL)
+	if err != nil {
+		return nil, err
+	}
+
+	urls = append(urls, files...)
+
+	if !b.scanComponents {
+		return urls, nil
+	}
+
+	targets, err := b.componentTargets()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, target := range targets {
+		files, err := b.planFiles(&target.application, target.targetURL)
+		if err != nil {
+			return nil, err
+		}
+
+		urls = append(urls, files...)
+	}
+
+	return urls, nil
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	u, _ := url.Parse("https://example.com/blog/")
+
+	b := &identify{
+		targetURL: u,
+		application: &Application{
+			Files: []string{"readme.txt", "css/style.css?ver=1"},
+			Components: map[string]Application{
+				"plugin": {
+					Path:  "plugins/{slug}/",
+					Files: []string{"readme.txt"},
+					Slugs: []string{"forms"},
+				},
+			},
+		},
+	}
+
+	b.noSoft404 = true
+
+	urls, err := b.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"https://example.com/blog/",
+		"https://example.com/blog/readme.txt",
+		"https://example.com/blog/css/style.css?ver=1",
+	}
+
+	if !reflect.DeepEqual(urls, expected) {
+		t.Fatalf("Unexpected plan: %v", urls)
+	}
+
+	b.noSoft404 = false
+
+	if urls, err = b.Plan(); err != nil {
+		t.Fatal(err)
+	} else if urls[len(urls)-1] != "https://example.com/blog/css/identify-not-found.css?ver=1" {
+		t.Fatalf("Expected not found probes in plan, got %v", urls)
+	}
+
+	b.noSoft404 = true
+	b.scanComponents = true
+
+	urls, err = b.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = append(expected, "https://example.com/blog/plugins/forms/readme.txt")
+
+	if !reflect.DeepEqual(urls, expected) {
+		t.Fatalf("Unexpected plan with components: %v", urls)
+	}
+
+	b.application.Components["theme"] = Application{
+		Path:  "themes/{slug}/",
+		Files: []string{"style.css"},
+		Slugs: []string{"classic"},
+	}
+
+	b.slugs = []string{"theme:dark"}
+
+	urls, err = b.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if u := urls[len(urls)-1]; len(urls) != 4 || u != "https://example.com/blog/themes/dark/style.css" {
+		t.Fatalf("Expected only the slugs of the kind, got %v", urls)
+	}
+
+	for _, slugs := range [][]string{{"dark"}, {"skin:dark"}, {"theme:../../admin"}} {
+		b.slugs = slugs
+
+		if _, err := b.Plan(); err == nil {
+			t.Errorf("Expected planning with slugs %v to fail", slugs)
+		}
+	}
+
+	for _, slug := range []string{"..", "plugin:../admin", ":forms", "plugin:Forms"} {
+		if _, err := Components([]string{slug}); err == nil {
+			t.Errorf("Expected slug %s to be invalid", slug)
+		}
+	}
+
+	b.application = nil
+
+	if _, err := b.Plan(); err == nil {
+		t.Fatalf("Expected planning without application to fail")
+	}
+}
@@ -0,0 +1,131 @@
+package app
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// Provenance records what produced a report, to be able to reproduce it:
+// the version of identify, the database, the state of the repository and
+// the options used.
+type Provenance struct {
+	ToolVersion string `json:"tool_version,omitempty"`
+
+	// Database is the sha-256 of the loaded database
+	Database string `json:"database_sha256,omitempty"`
+
+	Repository string     `json:"repository"`
+	Revision   string     `json:"revision,omitempty"`
+	Fetched    *time.Time `json:"fetched,omitempty"`
+
+	Options map[string]string `json:"options"`
+
+	// Addresses are the ip addresses the host of the target resolved to
+	Addresses []string `json:"addresses,omitempty"`
+}
+
+// settings returns the options of the identification that influence the
+// result, named after the flags.
+func (b *identify) settings() map[string]string {
+	s := map[string]string{
+		"redirects":     b.redirectPolicy,
+		"max-redirects": strconv.Itoa(b.maxRedirects),
+		"timeout":       b.client.Timeout.String(),
+		"retries":       strconv.Itoa(b.retries),
+		"backoff":       b.backoff.String(),
+		"max-body-size": strconv.FormatInt(b.maxBodySize, 10),
+	}
+
+	for name, enabled := range map[string]bool{
+		"no-branches": b.noBranches,
+		"no-tags":     b.noTags,
+		"no-soft404":  b.noSoft404,
+		"commits":     b.commits,
+		"components":  b.scanComponents,
+		"advisories":  b.advisories != nil,
+		"all-files":   b.allFiles,
+		"integrity":   b.integrity,
+	} {
+		if enabled {
+			s[name] = "true"
+		}
+	}
+
+	if b.overallTimeout > 0 {
+		s["overall-timeout"] = b.overallTimeout.String()
+	}
+
+	if b.commits {
+		s["max-commits"] = strconv.Itoa(b.maxCommits)
+	}
+
+	if len(b.slugs) > 0 {
+		s["slugs"] = strings.Join(b.slugs, ",")
+	}
+
+	if b.image != "" {
+		s["image"] = b.image
+	}
+
+	if b.capture != "" {
+		s["capture"] = b.capture
+	}
+
+	if b.proxyURL != nil {
+		// without credentials
+		u := *b.proxyURL
+		u.User = nil
+
+		s["proxy"] = u.String()
+	}
+
+	return s
+}
+
+// resolve returns the ip addresses the host of the target url resolves to.
+// Local targets and captured traffic aren't resolved, and neither are
+// targets requested through a proxy, which resolves them itself.
+func (b *identify) resolve() []string {
+	if isLocal(b.targetURL) || b.capture != "" || b.proxyURL != nil {
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(b.context(), b.targetURL.Hostname())
+	if err != nil {
+		fmt.Fprintln(b.out, color.RedString("[!] Could not resolve %s: %s", b.targetURL.Hostname(), err.Error()))
+		return nil
+	}
+
+	addresses := []string{}
+	for _, addr := range addrs {
+		addresses = append(addresses, addr.IP.String())
+	}
+
+	sort.Strings(addresses)
+	return addresses
+}
+
+// provenance returns the provenance of the report.
+func (b *identify) provenance() *Provenance {
+	p := &Provenance{
+		ToolVersion: b.toolVersion,
+		Database:    b.dbHash,
+		Repository:  b.application.Repository,
+		Revision:    b.revision,
+		Options:     b.settings(),
+		Addresses:   b.addresses,
+	}
+
+	if !b.fetched.IsZero() {
+		fetched := b.fetched.UTC()
+		p.Fetched = &fetched
+	}
+
+	return p
+}
@@ -0,0 +1,104 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	RedirectFollow   = "follow"
+	RedirectSameHost = "same-host"
+	RedirectNever    = "never"
+)
+
+const defaultMaxRedirects = 10
+
+// checkRedirect implements the configured redirect policy. Redirects that
+// are not followed return the redirect response itself, which will be
+// discarded because of its status code.
+func (b *identify) checkRedirect(req *http.Request, via []*http.Request) error {
+	switch b.redirectPolicy {
+	case RedirectNever:
+		return http.ErrUseLastResponse
+	case RedirectSameHost:
+		if !strings.EqualFold(req.URL.Host, via[0].URL.Host) {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	if b.maxRedirects == 0 {
+		return http.ErrUseLastResponse
+	} else if len(via) >= b.maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", b.maxRedirects)
+	}
+
+	return nil
+}
+
+// redirectChain returns the urls that have been requested to get resp, in
+// order. It returns nil when the request has not been redirected.
+func redirectChain(resp *http.Response) []string {
+	chain := []string{}
+
+	for r := resp.Request; r != nil; {
+		chain = append([]string{r.URL.String()}, chain...)
+
+		if r.Response == nil {
+			break
+		}
+
+		r = r.Response.Request
+	}
+
+	if len(chain) < 2 {
+		return nil
+	}
+
+	return chain
+}
+
+// redirectedElsewhere returns whether the request for u has been redirected
+// to another path, like a login page, whose response isn't the file.
+func redirectedElsewhere(resp *http.Response, u *url.URL) bool {
+	if resp.Request.Response == nil {
+		return false
+	}
+
+	return resp.Request.URL.Path != u.Path
+}
+
+func stripWWW(host string) string {
+	return strings.TrimPrefix(strings.ToLower(host), "www.")
+}
+
+// rebaseTargetURL requests the target url and will use the scheme and host it
+// redirects to, when the redirect is to https or the www variant of the same
+// host. Redirects are followed according to the redirect policy.
+func (b *identify) rebaseTargetURL() (*url.URL, error) {
+	resp, err := b.get(b.targetURL.String())
+	if err != nil {
+		return nil, err
+	}
+
+	closeBody(resp)
+
+	u := resp.Request.URL
+
+	if u.Scheme == b.targetURL.Scheme && u.Host == b.targetURL.Host {
+		return nil, nil
+	} else if u.Scheme != b.targetURL.Scheme && u.Scheme != "https" {
+		// never downgrade to http
+		return nil, nil
+	} else if u.Port() != b.targetURL.Port() {
+		return nil, nil
+	} else if stripWWW(u.Hostname()) != stripWWW(b.targetURL.Hostname()) {
+		return nil, nil
+	}
+
+	rebased := *b.targetURL
+	rebased.Scheme = u.Scheme
+	rebased.Host = u.Host
+	return &rebased, nil
+}
@@ -0,0 +1,130 @@
+package app
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRedirect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/readme.html":
+			http.Redirect(w, r, "/login", http.StatusFound)
+		case "/style.css":
+			if r.URL.RawQuery == "" {
+				http.Redirect(w, r, "/style.css?ver=1", http.StatusFound)
+				return
+			}
+
+			w.Write([]byte("body {}"))
+		default:
+			w.Write([]byte("<html>Login</html>"))
+		}
+	}))
+	defer server.Close()
+
+	b := &identify{}
+	b.redirectPolicy = RedirectSameHost
+	b.maxRedirects = defaultMaxRedirects
+
+	client := &http.Client{CheckRedirect: b.checkRedirect}
+
+	for path, expected := range map[string]bool{
+		"/readme.html": true,
+		"/style.css":   false,
+		"/index.html":  false,
+	} {
+		u, _ := url.Parse(server.URL + path)
+
+		resp, err := client.Get(u.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp.Body.Close()
+
+		if redirectedElsewhere(resp, u) != expected {
+			t.Errorf("Expected redirected elsewhere to be %t for %s", expected, path)
+		}
+	}
+
+	b.maxRedirects = 0
+
+	resp, err := client.Get(server.URL + "/readme.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusFound {
+		t.Errorf("Expected redirect not to be followed, got %d", resp.StatusCode)
+	}
+}
+
+// redirectTransport redirects the requests for the urls to their location,
+// other urls are served an empty page.
+type redirectTransport map[string]string
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}
+
+	if location, ok := t[req.URL.String()]; ok {
+		resp.StatusCode = http.StatusMovedPermanently
+		resp.Header.Set("Location", location)
+	}
+
+	return resp, nil
+}
+
+func TestRebaseTargetURL(t *testing.T) {
+	tests := []struct {
+		policy   string
+		target   string
+		location string
+		expected string
+	}{
+		{RedirectFollow, "http://example.com/", "https://example.com/", "https://example.com/"},
+		{RedirectFollow, "http://example.com/", "https://www.example.com/", "https://www.example.com/"},
+		{RedirectFollow, "https://www.example.com/", "https://example.com/", "https://example.com/"},
+		{RedirectFollow, "https://example.com/", "http://example.com/", ""},
+		{RedirectFollow, "http://example.com/", "http://example.com:8080/", ""},
+		{RedirectFollow, "http://example.com/", "https://example.org/", ""},
+		{RedirectSameHost, "http://example.com/", "https://www.example.com/", ""},
+		{RedirectNever, "http://example.com/", "https://example.com/", ""},
+	}
+
+	for _, test := range tests {
+		b := newTestIdentify(t, "", nil)
+		b.redirectPolicy = test.policy
+		b.maxRedirects = defaultMaxRedirects
+
+		b.client.Transport = redirectTransport{test.target: test.location}
+		b.client.CheckRedirect = b.checkRedirect
+
+		b.targetURL, _ = url.Parse(test.target)
+
+		u, err := b.rebaseTargetURL()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rebased := ""
+		if u != nil {
+			rebased = u.String()
+		}
+
+		if rebased != test.expected {
+			t.Errorf("Expected %s redirecting to %s with policy %s to be rebased to %q, got %q", test.target, test.location, test.policy, test.expected, rebased)
+		}
+	}
+}
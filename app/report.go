@@ -0,0 +1,278 @@
+package app
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	version "github.com/hashicorp/go-version"
+)
+
+// Report is the structured result of an identification.
+type Report struct {
+	Application string     `json:"application"`
+	Target      string     `json:"target"`
+	Component   *Component `json:"component,omitempty"`
+
+	Files    []*FileReport    `json:"files"`
+	Versions []*VersionReport `json:"versions"`
+	Commits  []*CommitMatch   `json:"commits,omitempty"`
+
+	// Differences are the files that differ from the best matching version
+	Differences []*FileDifference `json:"differences,omitempty"`
+
+	// Latest is the latest release known by the source
+	Latest string `json:"latest,omitempty"`
+
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+
+	Provenance *Provenance `json:"provenance,omitempty"`
+
+	// Bundle is the evidence bundle that has been written, the report in
+	// the bundle itself doesn't contain it
+	Bundle *BundleReport `json:"bundle,omitempty"`
+
+	Components []*Report `json:"components,omitempty"`
+}
+
+// FileReport is the evidence of a single fetched file.
+type FileReport struct {
+	Path       string   `json:"path"`
+	URL        string   `json:"url"`
+	StatusCode int      `json:"status_code"`
+	Hash       string   `json:"hash"`
+	Redirects  []string `json:"redirects,omitempty"`
+	Versions   []string `json:"versions"`
+
+	// Skipped is the reason the file hasn't been hashed, like a status code
+	// or a redirect to another path
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// hashedFiles returns the number of files that have been hashed, files
+// that have been skipped aren't.
+func (r *Report) hashedFiles() int {
+	count := 0
+
+	for _, file := range r.Files {
+		if file.Hash != "" {
+			count++
+		}
+	}
+
+	return count
+}
+
+// BundleReport is the file of an evidence bundle, with its sha-256.
+type BundleReport struct {
+	File   string `json:"file"`
+	SHA256 string `json:"sha256"`
+}
+
+// VersionReport is a candidate version, with the number of files matching
+// the version.
+type VersionReport struct {
+	Version    string      `json:"version"`
+	Matches    int         `json:"matches"`
+	Score      float64     `json:"score"`
+	CPE        string      `json:"cpe,omitempty"`
+	Purl       string      `json:"purl,omitempty"`
+	Advisories []*Advisory `json:"advisories,omitempty"`
+}
+
+// Best returns the best matching version, or nil if the application has not
+// been identified.
+func (r *Report) Best() *VersionReport {
+	if len(r.Versions) == 0 {
+		return nil
+	}
+
+	return r.Versions[0]
+}
+
+// Duration returns the time the identification took.
+func (r *Report) Duration() time.Duration {
+	return r.Finished.Sub(r.Started)
+}
+
+// Outdated returns true if the best matching version is older than the
+// latest release.
+func (r *Report) Outdated() bool {
+	best := r.Best()
+	if best == nil || r.Latest == "" {
+		return false
+	}
+
+	v, err := version.NewVersion(best.Version)
+	if err != nil {
+		return false
+	}
+
+	latest, err := version.NewVersion(r.Latest)
+	if err != nil {
+		return false
+	}
+
+	return v.LessThan(latest)
+}
+
+// WriteJSON writes the report as json to w.
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// Write writes the report in format to w, format is json, cyclonedx, sarif,
+// html, markdown or a text/template.
+func (r *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case "json":
+		return r.WriteJSON(w)
+	case "cyclonedx":
+		return r.WriteCycloneDX(w)
+	case "sarif":
+		return r.WriteSARIF(w)
+	case "html":
+		return WriteHTML(w, r)
+	case "markdown":
+		return WriteMarkdown(w, r)
+	}
+
+	if strings.Contains(format, "{{") {
+		return r.WriteTemplate(w, format)
+	}
+
+	return fmt.Errorf("Unknown report format: %s", format)
+}
+
+// latest returns the latest release of the versions, ignoring pre-releases
+// and versions that can't be parsed.
+func latest(versions []string) string {
+	var latest *version.Version
+	s := ""
+
+	for _, name := range versions {
+		v, err := version.NewVersion(name)
+		if err != nil || v.Prerelease() != "" {
+			continue
+		}
+
+		if latest == nil || v.GreaterThan(latest) {
+			latest = v
+			s = name
+		}
+	}
+
+	return s
+}
+
+// key returns the key of the application in the rule set and advisories.
+func (b *identify) key() string {
+	if b.component != nil {
+		return b.component.Slug
+	}
+
+	return b.targetApplication
+}
+
+// versionReports returns the candidate versions, ordered by the number of
+// matching files and version.
+func (b *identify) versionReports() []*VersionReport {
+	counts := map[string]int{}
+
+	for _, hash := range b.hashes {
+		for _, ref := range hash.Refs {
+			counts[ref.Name()]++
+		}
+	}
+
+	reports := []*VersionReport{}
+	for name, count := range counts {
+		vr := &VersionReport{
+			Version: name,
+			Matches: count,
+			Score:   float64(count) / float64(len(b.application.Files)),
+			CPE:     b.application.CPEName(name),
+			Purl:    b.application.PackageURL(name),
+		}
+
+		if b.advisories != nil {
+			vr.Advisories = b.advisories.For(b.key(), name)
+		}
+
+		reports = append(reports, vr)
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Matches != reports[j].Matches {
+			return reports[i].Matches > reports[j].Matches
+		}
+
+		vi, erri := version.NewVersion(reports[i].Version)
+		vj, errj := version.NewVersion(reports[j].Version)
+		if erri == nil && errj == nil {
+			return vi.GreaterThan(vj)
+		} else if erri == nil || errj == nil {
+			return erri == nil
+		}
+
+		return reports[i].Version < reports[j].Version
+	})
+
+	return reports
+}
+
+// Report returns the structured result of the identification.
+func (b *identify) Report() *Report {
+	r := &Report{
+		Application: b.application.Name,
+		Target:      b.target(),
+		Component:   b.component,
+		Files:       []*FileReport{},
+		Versions:    b.versionReports(),
+		Commits:     b.commitMatches,
+		Differences: b.differences,
+		Latest:      latest(b.versions),
+		Started:     b.started,
+		Finished:    b.finished,
+		Provenance:  b.provenance(),
+		Bundle:      b.bundleReport,
+	}
+
+	for _, file := range b.application.Files {
+		hash, ok := b.hashes[file]
+		if !ok {
+			if fr, ok := b.skipped[file]; ok {
+				r.Files = append(r.Files, fr)
+			}
+
+			continue
+		}
+
+		versions := []string{}
+		for _, ref := range hash.Refs {
+			versions = append(versions, ref.Name())
+		}
+
+		r.Files = append(r.Files, &FileReport{
+			Path:       file,
+			URL:        hash.URL,
+			StatusCode: hash.StatusCode,
+			Hash:       hex.EncodeToString(hash.Hash),
+			Redirects:  hash.Redirects,
+			Versions:   versions,
+		})
+	}
+
+	for _, c := range b.components {
+		r.Components = append(r.Components, c.Report())
+	}
+
+	return r
+}
@@ -0,0 +1,287 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testReport() *Report {
+	fetched := time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	return &Report{
+		Application: "wordpress",
+		Target:      "https://example.com/",
+		Files: []*FileReport{
+			{Path: "readme.html", URL: "https://example.com/readme.html", StatusCode: 200, Hash: "da39a3ee", Versions: []string{"4.7.1"}},
+			{Path: "license.txt", URL: "https://example.com/license.txt", StatusCode: 200, Hash: "a9993e36", Versions: []string{}},
+			{Path: "wp-login.php", URL: "https://example.com/wp-login.php", StatusCode: 200, Redirects: []string{"https://example.com/wp-login.php", "https://example.com/login"}, Versions: []string{}, Skipped: "redirected to another path"},
+		},
+		Versions: []*VersionReport{
+			{
+				Version: "4.7.1",
+				Matches: 1,
+				Score:   0.5,
+				CPE:     "cpe:2.3:a:wordpress:wordpress:4.7.1:*:*:*:*:*:*:*",
+				Advisories: []*Advisory{
+					{ID: "CVE-2017-1001000", Severity: "high", Fixed: "4.7.2"},
+				},
+			},
+		},
+		Latest: "4.9.0",
+		Provenance: &Provenance{
+			ToolVersion: "0.1",
+			Database:    "0c6f2b1e",
+			Repository:  "https://github.com/WordPress/WordPress",
+			Revision:    "a1b2c3d4",
+			Fetched:     &fetched,
+			Options:     map[string]string{"retries": "2"},
+			Addresses:   []string{"93.184.216.34"},
+		},
+	}
+}
+
+func TestReportFormats(t *testing.T) {
+	r := testReport()
+
+	for _, format := range []string{"json", "cyclonedx", "sarif", "html", "markdown"} {
+		buf := &bytes.Buffer{}
+		if err := r.Write(buf, format); err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+
+		if format == "html" || format == "markdown" {
+		} else if err := json.Unmarshal(buf.Bytes(), &map[string]interface{}{}); err != nil {
+			t.Fatalf("%s: invalid json: %s", format, err)
+		}
+
+		if !strings.Contains(buf.String(), "CVE-2017-1001000") {
+			t.Errorf("%s: expected advisory in report", format)
+		}
+
+		if format == "sarif" || format == "cyclonedx" {
+		} else if !strings.Contains(buf.String(), "redirected to another path") {
+			t.Errorf("%s: expected the reason a file has been skipped in report", format)
+		}
+
+		if format == "sarif" || format == "cyclonedx" {
+		} else if !strings.Contains(buf.String(), "a1b2c3d4") {
+			t.Errorf("%s: expected revision of repository in report", format)
+		}
+	}
+
+	if err := r.Write(&bytes.Buffer{}, "unknown"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestReportCycloneDXHost(t *testing.T) {
+	tests := map[string]string{
+		"https://example.com/":                "example.com",
+		"file:///srv/www/":                    "www",
+		"/images/application.tar":             "application.tar",
+		"/images/application.tar:/var/www/wp": "application.tar",
+	}
+
+	for target, expected := range tests {
+		r := testReport()
+		r.Target = target
+
+		buf := &bytes.Buffer{}
+		if err := r.WriteCycloneDX(buf); err != nil {
+			t.Fatal(err)
+		}
+
+		bom := cdxBOM{}
+		if err := json.Unmarshal(buf.Bytes(), &bom); err != nil {
+			t.Fatal(err)
+		} else if c := bom.Metadata.Component; c.Name != expected || c.BOMRef != expected {
+			t.Errorf("%s: expected host %s, got name %q and bom-ref %q", target, expected, c.Name, c.BOMRef)
+		}
+	}
+}
+
+func TestReportComponentJSON(t *testing.T) {
+	r := testReport()
+	r.Component = &Component{Kind: "plugin", Slug: "akismet"}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(data), `"component":{"kind":"plugin","slug":"akismet"}`) {
+		t.Errorf("Unexpected component in json: %s", data)
+	}
+}
+
+func TestReportSARIF(t *testing.T) {
+	rules := map[string]*sarifRule{}
+
+	results := testReport().sarifResults(rules)
+
+	ruleIDs := map[string]bool{}
+	for _, result := range results {
+		ruleIDs[result.RuleID] = true
+	}
+
+	for _, id := range []string{ruleOutdated, ruleModified, "CVE-2017-1001000"} {
+		if !ruleIDs[id] {
+			t.Errorf("Expected result for %s", id)
+		}
+	}
+
+	for _, result := range results {
+		if result.Locations[0].PhysicalLocation.ArtifactLocation.URI == "https://example.com/wp-login.php" {
+			t.Errorf("Expected no result for redirected file")
+		}
+	}
+}
+
+func TestReportSARIFDifferences(t *testing.T) {
+	r := testReport()
+	r.Files = r.Files[:1]
+	r.Latest = ""
+	r.Versions[0].Advisories = nil
+	r.Differences = []*FileDifference{
+		{Path: "wp-includes/version.php", Status: DifferenceModified},
+		{Path: "wp-includes/js/wp-emoji.js", Status: DifferenceMissing},
+		{Path: "wp-content/shell.php", Status: DifferenceUnknown},
+	}
+
+	results := r.sarifResults(map[string]*sarifRule{})
+
+	expected := map[string]string{
+		"wp-includes/version.php":    ruleModified,
+		"wp-includes/js/wp-emoji.js": ruleMissing,
+		"wp-content/shell.php":       ruleUnknown,
+	}
+
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d results, got %d", len(expected), len(results))
+	}
+
+	for _, result := range results {
+		if uri := result.Locations[0].PhysicalLocation.ArtifactLocation.URI; result.RuleID != expected[uri] {
+			t.Errorf("Expected rule %s for %s, got %s", expected[uri], uri, result.RuleID)
+		}
+	}
+
+	r.Versions = nil
+
+	if results := r.sarifResults(map[string]*sarifRule{}); len(results) != 0 {
+		t.Errorf("Expected no differences without a version, got %d results", len(results))
+	}
+}
+
+func TestReportOutdated(t *testing.T) {
+	r := testReport()
+
+	if !r.Outdated() {
+		t.Errorf("Expected 4.7.1 to be outdated by %s", r.Latest)
+	}
+
+	r.Latest = "not a version"
+
+	if r.Outdated() {
+		t.Errorf("Expected invalid latest release not to be outdated")
+	}
+}
+
+func TestLatest(t *testing.T) {
+	if v := latest([]string{"master", "4.7.1", "4.9.0-beta1", "4.8.0"}); v != "4.8.0" {
+		t.Errorf("Expected 4.8.0, got %s", v)
+	}
+}
+
+func TestReportTemplate(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	if err := testReport().Write(buf, `{{.Target}} {{.Best.Version}} {{percent .Best.Score}} {{join (candidates .) ","}}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := buf.String(); s != "https://example.com/ 4.7.1 50% 4.7.1\n" {
+		t.Errorf("Unexpected output: %q", s)
+	}
+}
+
+func TestReportSkippedFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "identify")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	writeZip(t, filepath.Join(dir, "application-1.0.0.zip"), map[string]string{
+		"application-1.0.0/readme.txt": "1.0.0",
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/readme.txt":
+			w.Write([]byte("1.0.0"))
+		case "/private.txt":
+			w.WriteHeader(http.StatusForbidden)
+		case "/license.txt":
+			http.Redirect(w, r, "/login", http.StatusFound)
+		case "/login":
+			w.Write([]byte("<html>Login</html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	b := newTestIdentify(t, dir, &Application{
+		Name:       "application",
+		Type:       SourceArchive,
+		Repository: dir,
+		Files:      []string{"readme.txt", "missing.txt", "private.txt", "license.txt"},
+	})
+
+	b.noSoft404 = true
+	b.redirectPolicy = RedirectFollow
+	b.maxRedirects = defaultMaxRedirects
+	b.client.CheckRedirect = b.checkRedirect
+	b.targetURL, _ = url.Parse(server.URL + "/")
+
+	if err := b.identify(); err != nil {
+		t.Fatal(err)
+	}
+
+	// every requested file is reported, with its status code
+	expected := map[string]FileReport{
+		"readme.txt":  {StatusCode: http.StatusOK},
+		"missing.txt": {StatusCode: http.StatusNotFound, Skipped: "status code 404"},
+		"private.txt": {StatusCode: http.StatusForbidden, Skipped: "status code 403"},
+		"license.txt": {StatusCode: http.StatusOK, Skipped: "redirected to another path"},
+	}
+
+	files := b.Report().Files
+	if len(files) != len(expected) {
+		t.Fatalf("Expected %d files, got %d", len(expected), len(files))
+	}
+
+	for _, file := range files {
+		e := expected[file.Path]
+		if file.StatusCode != e.StatusCode || file.Skipped != e.Skipped {
+			t.Errorf("Expected %s with status code %d skipped %q, got %d skipped %q", file.Path, e.StatusCode, e.Skipped, file.StatusCode, file.Skipped)
+		} else if (file.Hash == "") != (e.Skipped != "") {
+			t.Errorf("Expected only files that aren't skipped to be hashed, got %s with hash %q", file.Path, file.Hash)
+		}
+	}
+
+	if redirects := files[3].Redirects; len(redirects) != 2 {
+		t.Errorf("Expected the redirects of license.txt, got %v", redirects)
+	}
+}
@@ -1,12 +1,17 @@
 package app
 
-import "gopkg.in/src-d/go-git.v4/plumbing"
-
 type Result struct {
 	Hash []byte
-	Refs []*plumbing.Reference
+	Refs []Reference
+
+	URL        string
+	StatusCode int
+
+	// Redirects contains the urls requested to fetch the file, when the
+	// request has been redirected
+	Redirects []string
 }
 
-func (r *Result) AddRef(ref *plumbing.Reference) {
+func (r *Result) AddRef(ref Reference) {
 	r.Refs = append(r.Refs, ref)
 }
@@ -0,0 +1,207 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	ruleOutdated = "identify/outdated-version"
+	ruleModified = "identify/modified-file"
+	ruleMissing  = "identify/missing-file"
+	ruleUnknown  = "identify/unknown-file"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool      `json:"tool"`
+	Results []*sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string       `json:"name"`
+	Version        string       `json:"version,omitempty"`
+	InformationURI string       `json:"informationUri"`
+	Rules          []*sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	HelpURI          string       `json:"helpUri,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+func sarifLevel(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical", "high":
+		return "error"
+	case "low", "info", "none":
+		return "note"
+	default:
+		return "warning"
+	}
+}
+
+func newSarifResult(ruleID string, level string, uri string, format string, args ...interface{}) *sarifResult {
+	return &sarifResult{
+		RuleID:  ruleID,
+		Level:   level,
+		Message: sarifMessage{Text: fmt.Sprintf(format, args...)},
+		Locations: []sarifLocation{
+			{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: uri}}},
+		},
+	}
+}
+
+// sarifResults returns the findings of the report and its components: an
+// outdated version, files that don't match any version and advisories of the
+// best matching version.
+func (r *Report) sarifResults(rules map[string]*sarifRule) []*sarifResult {
+	results := []*sarifResult{}
+
+	best := r.Best()
+	if best == nil {
+	} else if r.Outdated() {
+		results = append(results, newSarifResult(ruleOutdated, "warning", r.Target, "%s %s is outdated, the latest release is %s", r.Application, best.Version, r.Latest))
+	}
+
+	for _, file := range r.Files {
+		if len(file.Versions) > 0 || file.Hash == "" {
+			continue
+		}
+
+		results = append(results, newSarifResult(ruleModified, "warning", file.URL, "%s doesn't match any known version of %s", file.Path, r.Application))
+	}
+
+	for _, difference := range r.Differences {
+		if best == nil {
+			break
+		}
+
+		switch difference.Status {
+		case DifferenceUnknown:
+			results = append(results, newSarifResult(ruleUnknown, "note", difference.Path, "%s isn't part of %s %s", difference.Path, r.Application, best.Version))
+		case DifferenceMissing:
+			results = append(results, newSarifResult(ruleMissing, "note", difference.Path, "%s of %s %s is missing", difference.Path, r.Application, best.Version))
+		default:
+			results = append(results, newSarifResult(ruleModified, "warning", difference.Path, "%s differs from %s %s", difference.Path, r.Application, best.Version))
+		}
+	}
+
+	if best != nil {
+		for _, advisory := range best.Advisories {
+			rule := &sarifRule{
+				ID:               advisory.ID,
+				ShortDescription: sarifMessage{Text: advisory.Summary},
+			}
+
+			if len(advisory.References) > 0 {
+				rule.HelpURI = advisory.References[0]
+			}
+
+			rules[advisory.ID] = rule
+
+			results = append(results, newSarifResult(advisory.ID, sarifLevel(advisory.Severity), r.Target, "%s %s is affected by %s, fixed in %s", r.Application, best.Version, advisory.ID, advisory.Fixed))
+		}
+	}
+
+	for _, component := range r.Components {
+		results = append(results, component.sarifResults(rules)...)
+	}
+
+	return results
+}
+
+// WriteSARIF writes the findings of the report as SARIF to w.
+func (r *Report) WriteSARIF(w io.Writer) error {
+	rules := map[string]*sarifRule{
+		ruleOutdated: {
+			ID:               ruleOutdated,
+			ShortDescription: sarifMessage{Text: "The identified version is older than the latest release"},
+		},
+		ruleModified: {
+			ID:               ruleModified,
+			ShortDescription: sarifMessage{Text: "The file doesn't match any known version of the application"},
+		},
+		ruleMissing: {
+			ID:               ruleMissing,
+			ShortDescription: sarifMessage{Text: "The file of the identified version of the application doesn't exist"},
+		},
+		ruleUnknown: {
+			ID:               ruleUnknown,
+			ShortDescription: sarifMessage{Text: "The file isn't part of the identified version of the application"},
+		},
+	}
+
+	results := r.sarifResults(rules)
+
+	ids := []string{}
+	for id := range rules {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	driver := sarifDriver{
+		Name:           "identify",
+		InformationURI: "https://github.com/dutchcoders/identify",
+	}
+
+	if r.Provenance != nil {
+		driver.Version = r.Provenance.ToolVersion
+	}
+
+	for _, id := range ids {
+		driver.Rules = append(driver.Rules, rules[id])
+	}
+
+	log := &sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{
+			{
+				Tool:    sarifTool{Driver: driver},
+				Results: results,
+			},
+		},
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(log)
+}
@@ -0,0 +1,427 @@
+package app
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	JobQueued   = "queued"
+	JobRunning  = "running"
+	JobFinished = "finished"
+	JobFailed   = "failed"
+)
+
+const (
+	// finished jobs are forgotten after retention, or when there are more
+	// than maxJobs jobs, the oldest first.
+	defaultJobRetention = 24 * time.Hour
+	defaultMaxJobs      = 1000
+
+	maxRequestSize = 1 << 20
+)
+
+// jobLog is the console output of a job, safe for concurrent use.
+type jobLog struct {
+	m   sync.Mutex
+	buf bytes.Buffer
+}
+
+func (l *jobLog) Write(p []byte) (int, error) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	return l.buf.Write(p)
+}
+
+func (l *jobLog) String() string {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	return l.buf.String()
+}
+
+// Job is an identification submitted to the server.
+type Job struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	Application string    `json:"application"`
+	Status      string    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	Version     string    `json:"version,omitempty"`
+	Score       float64   `json:"score,omitempty"`
+	Created     time.Time `json:"created"`
+	Started     time.Time `json:"started"`
+	Finished    time.Time `json:"finished"`
+
+	options []OptionFn
+	log     *jobLog
+	report  *Report
+}
+
+// Server runs identifications submitted over http, sharing one index
+// between them and running at most concurrency jobs at the same time.
+type Server struct {
+	index   *Index
+	options []OptionFn
+	sem     chan struct{}
+
+	token     string
+	retention time.Duration
+	maxJobs   int
+
+	m    sync.Mutex
+	jobs map[string]*Job
+
+	mux *http.ServeMux
+}
+
+// NewServer returns a server identifying targets with the options, which
+// shouldn't contain a target url, application or output.
+func NewServer(index *Index, concurrency int, options ...OptionFn) (*Server, error) {
+	if concurrency <= 0 {
+		return nil, fmt.Errorf("Invalid concurrency: %d", concurrency)
+	}
+
+	s := &Server{
+		index:     index,
+		options:   options,
+		sem:       make(chan struct{}, concurrency),
+		retention: defaultJobRetention,
+		maxJobs:   defaultMaxJobs,
+		jobs:      map[string]*Job{},
+		mux:       http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("/api/scans", s.handleScans)
+	s.mux.HandleFunc("/api/scans/", s.handleScan)
+	s.mux.HandleFunc("/", s.handleDashboard)
+
+	return s, nil
+}
+
+// RequireToken requires api requests to authenticate with token as bearer
+// token.
+func (s *Server) RequireToken(token string) {
+	s.token = token
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, "/api/") {
+	} else if s.token == "" {
+	} else if auth := r.Header.Get("Authorization"); subtle.ConstantTimeCompare([]byte(auth), []byte("Bearer "+s.token)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	s.mux.ServeHTTP(w, r)
+}
+
+// Submit queues the identification of application on targetURL, the
+// application is detected when empty or auto.
+func (s *Server) Submit(targetURL string, application string) (*Job, error) {
+	if application == "" {
+		application = ApplicationAuto
+	}
+
+	// local paths would expose the filesystem of the server
+	if u, err := url.Parse(targetURL); err != nil {
+		return nil, err
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("Unsupported scheme: %s", u.Scheme)
+	}
+
+	id, err := randomName()
+	if err != nil {
+		return nil, err
+	}
+
+	job := &Job{
+		ID:          id,
+		URL:         targetURL,
+		Application: application,
+		Status:      JobQueued,
+		Created:     time.Now(),
+		log:         &jobLog{},
+	}
+
+	if fn, err := TargetURL(targetURL); err != nil {
+		return nil, err
+	} else {
+		job.options = append(job.options, fn)
+	}
+
+	if fn, err := TargetApplication(application); err != nil {
+		return nil, err
+	} else {
+		job.options = append(job.options, fn)
+	}
+
+	if fn, err := Output(job.log); err != nil {
+		return nil, err
+	} else {
+		job.options = append(job.options, fn)
+	}
+
+	if fn, err := SharedIndex(s.index); err != nil {
+		return nil, err
+	} else {
+		job.options = append(job.options, fn)
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.expire(time.Now()); len(s.jobs) >= s.maxJobs {
+		return nil, fmt.Errorf("Too many jobs: %d", len(s.jobs))
+	}
+
+	s.jobs[job.ID] = job
+
+	go s.run(job)
+
+	return job, nil
+}
+
+// expire forgets the jobs finished before the retention, and the oldest
+// finished jobs when there are too many. The lock should be held.
+func (s *Server) expire(now time.Time) {
+	finished := []*Job{}
+
+	for id, job := range s.jobs {
+		if job.Status != JobFinished && job.Status != JobFailed {
+			continue
+		} else if now.Sub(job.Finished) > s.retention {
+			delete(s.jobs, id)
+			continue
+		}
+
+		finished = append(finished, job)
+	}
+
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].Finished.Before(finished[j].Finished)
+	})
+
+	for _, job := range finished {
+		if len(s.jobs) < s.maxJobs {
+			break
+		}
+
+		delete(s.jobs, job.ID)
+	}
+}
+
+// Job returns a copy of the job with id.
+func (s *Server) Job(id string) (Job, bool) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+
+	return *job, true
+}
+
+// Jobs returns copies of all jobs, the most recent first.
+func (s *Server) Jobs() []Job {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	jobs := []Job{}
+	for _, job := range s.jobs {
+		jobs = append(jobs, *job)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].Created.After(jobs[j].Created)
+	})
+
+	return jobs
+}
+
+func (s *Server) update(job *Job, fn func()) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	fn()
+}
+
+func (s *Server) run(job *Job) {
+	s.sem <- struct{}{}
+	defer func() {
+		<-s.sem
+	}()
+
+	s.update(job, func() {
+		job.Status = JobRunning
+		job.Started = time.Now()
+	})
+
+	report, err := s.identify(job)
+
+	s.update(job, func() {
+		job.Finished = time.Now()
+
+		if err != nil {
+			job.Status = JobFailed
+			job.Error = err.Error()
+			return
+		}
+
+		job.Status = JobFinished
+		job.Application = report.Application
+		job.report = report
+
+		if best := report.Best(); best != nil {
+			job.Version = best.Version
+			job.Score = best.Score
+		}
+	})
+}
+
+func (s *Server) identify(job *Job) (report *Report, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Identification panicked: %v", r)
+		}
+	}()
+
+	options := append(append([]OptionFn{}, s.options...), job.options...)
+
+	b, err := New(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := b.Identify(); err != nil {
+		return nil, err
+	}
+
+	return b.Report(), nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, format string, args ...interface{}) {
+	writeJSON(w, code, map[string]string{
+		"error": fmt.Sprintf(format, args...),
+	})
+}
+
+// sameOrigin returns whether the request wasn't sent by a browser from
+// another site. Browsers set the origin on cross-origin posts.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return u.Host == r.Host
+}
+
+// handleScans lists the jobs, or submits a job from a json body with url
+// and application. Only json is accepted, as browsers can't post json to
+// another site without its consent.
+func (s *Server) handleScans(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.Jobs())
+	case http.MethodPost:
+		req := struct {
+			URL         string `json:"url"`
+			Application string `json:"application"`
+		}{}
+
+		if !sameOrigin(r) {
+			writeError(w, http.StatusForbidden, "Cross origin request")
+			return
+		} else if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+			writeError(w, http.StatusUnsupportedMediaType, "Content type should be application/json")
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Could not decode request: %s", err.Error())
+			return
+		}
+
+		if req.URL == "" {
+			writeError(w, http.StatusBadRequest, "No target url set")
+			return
+		}
+
+		job, err := s.Submit(req.URL, req.Application)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Could not submit job: %s", err.Error())
+			return
+		}
+
+		status, _ := s.Job(job.ID)
+
+		w.Header().Set("Location", "/api/scans/"+job.ID)
+		writeJSON(w, http.StatusAccepted, status)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleScan returns the status of a job at /api/scans/{id}, its report at
+// /api/scans/{id}/report and its console output at /api/scans/{id}/log.
+func (s *Server) handleScan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/scans/"), "/")
+
+	job, ok := s.Job(parts[0])
+	if !ok {
+		writeError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+
+	switch strings.Join(parts[1:], "/") {
+	case "":
+		writeJSON(w, http.StatusOK, job)
+	case "report":
+		if job.report == nil {
+			writeError(w, http.StatusConflict, "Job is %s", job.Status)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, job.report)
+	case "log":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, job.log.String())
+	default:
+		writeError(w, http.StatusNotFound, "Not found")
+	}
+}
@@ -0,0 +1,212 @@
+package app
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testServer(t *testing.T) (*Server, *httptest.Server, func()) {
+	dir, err := ioutil.TempDir("", "identify")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile("../db.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "db.yaml"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	target := httptest.NewServer(http.NotFoundHandler())
+
+	cachePath, _ := CachePath(dir)
+	retries, _ := Retries(0)
+
+	s, err := NewServer(NewIndex(time.Hour), 2, cachePath, retries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s, target, func() {
+		target.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func waitJob(t *testing.T, s *Server, id string) Job {
+	for i := 0; i < 100; i++ {
+		if job, _ := s.Job(id); job.Status == JobFinished || job.Status == JobFailed {
+			return job
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	t.Fatal("Timeout waiting for job")
+	return Job{}
+}
+
+func TestServer(t *testing.T) {
+	s, target, cleanup := testServer(t)
+	defer cleanup()
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/api/scans", strings.NewReader(`{"url": "`+target.URL+`/", "application": "wordpress"}`)))
+
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("Expected request without json content type to fail, got %d", w.Code)
+	}
+
+	r := httptest.NewRequest("POST", "/api/scans", strings.NewReader(`{"url": "`+target.URL+`/", "application": "wordpress"}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Origin", "https://attacker.example")
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected cross origin request to fail, got %d", w.Code)
+	}
+
+	r = httptest.NewRequest("POST", "/api/scans", strings.NewReader(`{"url": "`+strings.Repeat("a", maxRequestSize)+`"}`))
+	r.Header.Set("Content-Type", "application/json")
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected too large request to fail, got %d", w.Code)
+	}
+
+	r = httptest.NewRequest("POST", "/api/scans", strings.NewReader(`{"url": "`+target.URL+`/", "application": "wordpress"}`))
+	r.Header.Set("Content-Type", "application/json")
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status accepted, got %d: %s", w.Code, w.Body.String())
+	}
+
+	job := Job{}
+	if err := json.NewDecoder(w.Body).Decode(&job); err != nil {
+		t.Fatal(err)
+	}
+
+	if job = waitJob(t, s, job.ID); job.Status != JobFinished {
+		t.Fatalf("Expected job to finish, got %s: %s", job.Status, job.Error)
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/api/scans/"+job.ID+"/report", nil))
+
+	report := Report{}
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	} else if report.Application != "wordpress" {
+		t.Errorf("Unexpected application: %s", report.Application)
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/api/scans/unknown", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status not found, got %d", w.Code)
+	}
+}
+
+func TestServerAuto(t *testing.T) {
+	s, target, cleanup := testServer(t)
+	defer cleanup()
+
+	job, err := s.Submit(target.URL+"/", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if j := waitJob(t, s, job.ID); j.Status != JobFailed {
+		t.Errorf("Expected detection to fail, got %s", j.Status)
+	}
+}
+
+func TestServerToken(t *testing.T) {
+	s, _, cleanup := testServer(t)
+	defer cleanup()
+
+	s.RequireToken("secret")
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/api/scans", nil))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected request without token to fail, got %d", w.Code)
+	}
+
+	r := httptest.NewRequest("GET", "/api/scans", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected request with token to succeed, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected dashboard without token, got %d", w.Code)
+	}
+}
+
+func TestServerExpire(t *testing.T) {
+	s, _, cleanup := testServer(t)
+	defer cleanup()
+
+	now := time.Now()
+
+	s.maxJobs = 2
+	s.jobs = map[string]*Job{
+		"expired": {ID: "expired", Status: JobFinished, Finished: now.Add(-2 * s.retention)},
+		"oldest":  {ID: "oldest", Status: JobFailed, Finished: now.Add(-2 * time.Hour)},
+		"newest":  {ID: "newest", Status: JobFinished, Finished: now.Add(-time.Hour)},
+		"running": {ID: "running", Status: JobRunning},
+	}
+
+	s.expire(now)
+
+	if _, ok := s.jobs["expired"]; ok {
+		t.Errorf("Expected expired job to be forgotten")
+	} else if _, ok := s.jobs["oldest"]; ok {
+		t.Errorf("Expected oldest finished job to be forgotten")
+	} else if _, ok := s.jobs["running"]; !ok {
+		t.Errorf("Expected running job to be kept")
+	} else if len(s.jobs) != 1 {
+		t.Errorf("Expected 1 job, got %d", len(s.jobs))
+	}
+}
+
+func TestServerDashboard(t *testing.T) {
+	s, _, cleanup := testServer(t)
+	defer cleanup()
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status ok, got %d", w.Code)
+	} else if strings.Contains(w.Body.String(), "<script src=") {
+		t.Errorf("Expected dashboard without external scripts")
+	}
+}
@@ -0,0 +1,224 @@
+package app
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// softNotFound contains the fingerprint of the response a target returns for
+// a path that doesn't exist, for servers that answer unknown paths with
+// 200 OK and a generic error page.
+type softNotFound struct {
+	StatusCode  int
+	ContentType string
+	Hash        []byte
+}
+
+// htmlExtensions are the extensions for which a text/html response is
+// expected.
+var htmlExtensions = map[string]bool{
+	"":       true,
+	".htm":   true,
+	".html":  true,
+	".php":   true,
+	".xhtml": true,
+}
+
+// capturedProbeName is the name of the non-existent file that is requested
+// in capture mode when no probe names have been stored, so the probes can be
+// part of a plan.
+const capturedProbeName = "identify-not-found"
+
+func randomName() (string, error) {
+	data := make([]byte, 16)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(data), nil
+}
+
+// normalizeBody removes the requested path from the body, most soft-404 pages
+// echo the requested path, which would make every fingerprint unique.
+func normalizeBody(body []byte, p string) []byte {
+	for _, s := range []string{p, url.PathEscape(p), path.Base(p)} {
+		if s == "" || s == "." || s == "/" {
+			continue
+		}
+
+		body = bytes.Replace(body, []byte(s), []byte{}, -1)
+	}
+
+	return body
+}
+
+func mediaType(resp *http.Response) string {
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		return ""
+	}
+
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+
+	return strings.ToLower(mt)
+}
+
+// probeKey returns the directory and extension of file, like css/*.css, not
+// found pages are fingerprinted per directory and extension as servers often
+// handle them differently per directory.
+func probeKey(file string) string {
+	return path.Join(path.Dir(file), "*"+strings.ToLower(path.Ext(file)))
+}
+
+// probeFiles returns the first file of every directory and extension in
+// files.
+func probeFiles(files []string) []string {
+	seen := map[string]bool{}
+
+	probes := []string{}
+	for _, file := range files {
+		key := probeKey(file)
+		if seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		probes = append(probes, file)
+	}
+
+	return probes
+}
+
+// probePath returns the path of the non-existent file with name, in the
+// directory and with the extension of file.
+func probePath(file string, name string) string {
+	return path.Join(path.Dir(file), name+strings.ToLower(path.Ext(file)))
+}
+
+// probeName returns the name of the non-existent file requested for ext: the
+// stored name when reanalyzing, a fixed name for other captured traffic and
+// a random name otherwise. Names are recorded to be stored with the evidence.
+func (b *identify) probeName(ext string) (string, error) {
+	if b.probes == nil {
+		b.probes = map[string]string{}
+	}
+
+	if name, ok := b.probes[ext]; ok {
+		return name, nil
+	} else if b.capture != "" {
+		return capturedProbeName, nil
+	}
+
+	name, err := randomName()
+	if err != nil {
+		return "", err
+	}
+
+	b.probes[ext] = name
+	return name, nil
+}
+
+// fingerprintNotFound requests a random non-existent path for every directory
+// and extension in the application files and fingerprints the responses that
+// are returned with a 2xx status code. Probes that fail aren't fingerprinted.
+func (b *identify) fingerprintNotFound() (map[string]*softNotFound, error) {
+	fingerprints := map[string]*softNotFound{}
+
+	for _, file := range probeFiles(b.application.Files) {
+		name, err := b.probeName(strings.ToLower(path.Ext(file)))
+		if err != nil {
+			return nil, err
+		}
+
+		rel, err := url.Parse(probePath(file, name))
+		if err != nil {
+			return nil, err
+		}
+
+		abs := b.targetURL.ResolveReference(rel)
+
+		resp, err := b.get(abs.String())
+		if err != nil {
+			fmt.Fprintln(b.out, color.RedString("[!] Could not fingerprint not found page %s: %s", abs.String(), err.Error()))
+			continue
+		}
+
+		if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+			// server returns a proper error, nothing to fingerprint
+			closeBody(resp)
+
+			fingerprints[probeKey(file)] = nil
+			continue
+		}
+
+		body, err := readBody(resp, b.maxBodySize)
+		if err != nil {
+			fmt.Fprintln(b.out, color.RedString("[!] Could not fingerprint not found page %s: %s", abs.String(), err.Error()))
+			continue
+		}
+
+		hash, err := CalcHash(ioutil.NopCloser(bytes.NewReader(normalizeBody(body, abs.Path))))
+		if err != nil {
+			return nil, err
+		}
+
+		fingerprints[probeKey(file)] = &softNotFound{
+			StatusCode:  resp.StatusCode,
+			ContentType: mediaType(resp),
+			Hash:        hash,
+		}
+	}
+
+	return fingerprints, nil
+}
+
+// notFoundFingerprint returns the fingerprint of the directory of file, or of
+// the closest parent directory that has been probed for its extension. It
+// returns nil when there is nothing to fingerprint.
+func notFoundFingerprint(fingerprints map[string]*softNotFound, file string) *softNotFound {
+	ext := strings.ToLower(path.Ext(file))
+
+	for dir := path.Dir(file); ; dir = path.Dir(dir) {
+		if fingerprint, ok := fingerprints[path.Join(dir, "*"+ext)]; ok {
+			return fingerprint
+		} else if dir == "." || dir == "/" {
+			return nil
+		}
+	}
+}
+
+// isSoftNotFound returns a reason when the response for file looks like a
+// soft-404, either because the body matches the fingerprinted error page or
+// because the content type doesn't fit the extension of the file.
+func isSoftNotFound(fingerprints map[string]*softNotFound, file string, resp *http.Response, body []byte) (string, bool) {
+	ext := strings.ToLower(path.Ext(file))
+
+	if mt := mediaType(resp); mt == "text/html" && !htmlExtensions[ext] {
+		return "content type " + mt + " doesn't match extension " + ext, true
+	}
+
+	fingerprint := notFoundFingerprint(fingerprints, file)
+	if fingerprint == nil {
+		return "", false
+	}
+
+	hash, err := CalcHash(ioutil.NopCloser(bytes.NewReader(normalizeBody(body, resp.Request.URL.Path))))
+	if err != nil || !bytes.Equal(hash, fingerprint.Hash) {
+		return "", false
+	}
+
+	return "body matches not found page", true
+}
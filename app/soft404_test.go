@@ -0,0 +1,92 @@
+package app
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSoftNotFound(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/readme.html":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, "<html>WordPress</html>")
+		case "/style.css":
+			w.Header().Set("Content-Type", "text/css")
+			fmt.Fprint(w, "body {}")
+		case "/admin/index.html":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, "<html>Admin</html>")
+		case "/broken/index.html":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, "<html>Broken</html>")
+		default:
+			if strings.HasPrefix(r.URL.Path, "/broken/") {
+				// the connection fails for unknown paths
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+				return
+			} else if strings.HasPrefix(r.URL.Path, "/admin/") {
+				w.Header().Set("Content-Type", "text/html")
+				fmt.Fprint(w, "<html>Admin page not found</html>")
+				return
+			}
+
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprintf(w, "<html>Page %s not found</html>", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	targetURL, _ := url.Parse(ts.URL + "/")
+
+	b := &identify{
+		client: ts.Client(),
+		application: &Application{
+			Files: []string{"readme.html", "style.css", "missing.html", "missing.css", "broken/index.html", "admin/index.html", "admin/missing.html"},
+		},
+		out: ioutil.Discard,
+	}
+	b.targetURL = targetURL
+	b.maxBodySize = defaultMaxBodySize
+
+	fingerprints, err := b.fingerprintNotFound()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a failing probe doesn't drop the other fingerprints, and each
+	// directory has its own fingerprint or the one of its parent
+	if _, ok := fingerprints["broken/*.html"]; ok {
+		t.Error("Expected no fingerprint for the failed probe")
+	}
+
+	expected := map[string]bool{
+		"readme.html":           false,
+		"style.css":             false,
+		"missing.html":          true,
+		"missing.css":           true,
+		"admin/index.html":      false,
+		"admin/missing.html":    true,
+		"admin/js/missing.html": true,
+	}
+
+	for file, soft := range expected {
+		resp, err := b.client.Get(ts.URL + "/" + file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if _, ok := isSoftNotFound(fingerprints, file, resp, body); ok != soft {
+			t.Errorf("isSoftNotFound(%s): expected %t, got %t", file, soft, ok)
+		}
+	}
+}
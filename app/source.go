@@ -0,0 +1,122 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+const (
+	SourceGit       = "git"
+	SourceMercurial = "hg"
+	SourceSVN       = "svn"
+	SourceArchive   = "archive"
+)
+
+// Source provides the versions of an application, the repository or
+// collection of archives the application is being distributed with.
+type Source interface {
+	// Update retrieves the latest versions into the cache
+	Update() error
+
+	Branches() ([]Reference, error)
+	Tags() ([]Reference, error)
+}
+
+// Reference is a single version of the application, like a git tag or a
+// release archive.
+type Reference interface {
+	Name() string
+
+	// Open returns the contents of the file with name, relative to the root
+	// of the source. It returns ErrFileNotFound when the file doesn't exist.
+	Open(name string) (io.ReadCloser, error)
+}
+
+// ErrFileNotFound is returned by references for files that don't exist in
+// the version.
+var ErrFileNotFound = errors.New("File not found")
+
+// commandError is returned when the command of a source fails.
+type commandError struct {
+	command string
+	err     error
+	stderr  string
+}
+
+func (e *commandError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", e.command, e.err.Error(), e.stderr)
+}
+
+// exitCode returns the exit status of the command, or -1 if it didn't run or
+// has been killed.
+func (e *commandError) exitCode() int {
+	if ee, ok := e.err.(*exec.ExitError); ok {
+		return ee.ExitCode()
+	}
+
+	return -1
+}
+
+// Walker is implemented by references that can list their files.
+type Walker interface {
+	// Walk calls fn with the name of every file under dir, relative to the
+	// root of the source.
+	Walk(dir string, fn func(name string) error) error
+}
+
+// Revisioner is implemented by sources that know the revision of the
+// repository they have been updated to.
+type Revisioner interface {
+	Revision() (string, error)
+}
+
+// inDir returns true if the file name is in dir or one of its
+// subdirectories.
+func inDir(name string, dir string) bool {
+	dir = strings.Trim(dir, "/")
+	if dir == "" || dir == "." {
+		return true
+	}
+
+	return strings.HasPrefix(name, dir+"/")
+}
+
+// NewSource returns the source for the application, using cachePath to store
+// the retrieved versions, fetch to download release archives and writing
+// progress to out.
+func NewSource(application *Application, cachePath string, fetch FetchFn, out io.Writer) (Source, error) {
+	sourceCachePath := path.Join(cachePath, hashStr(application.Repository))
+
+	switch application.Type {
+	case "", SourceGit:
+		return &gitSource{
+			repository: application.Repository,
+			cachePath:  sourceCachePath,
+			out:        out,
+		}, nil
+	case SourceMercurial:
+		return &hgSource{
+			repository: application.Repository,
+			cachePath:  sourceCachePath,
+			out:        out,
+		}, nil
+	case SourceSVN:
+		return &svnSource{
+			repository: application.Repository,
+		}, nil
+	case SourceArchive:
+		return &archiveSource{
+			repository: application.Repository,
+			archives:   application.Archives,
+			cachePath:  sourceCachePath,
+			fetch:      fetch,
+			out:        out,
+		}, nil
+	default:
+		return nil, fmt.Errorf("Unknown source type: %s", application.Type)
+	}
+}
@@ -0,0 +1,387 @@
+package app
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/fatih/color"
+)
+
+// archiveSource retrieves versions from release archives, every archive is
+// a version of the application. Archives are either listed explicitly with
+// their version or found in the directory the repository points to.
+type archiveSource struct {
+	repository string
+	archives   map[string]string
+	cachePath  string
+
+	// fetch downloads the archives that are urls
+	fetch FetchFn
+
+	out io.Writer
+
+	refs []Reference
+}
+
+type archiveReference struct {
+	name string
+	root string
+}
+
+func (ref *archiveReference) Name() string {
+	return ref.name
+}
+
+func (ref *archiveReference) Open(name string) (io.ReadCloser, error) {
+	// a file in the path of name isn't found either
+	f, err := os.Open(filepath.Join(ref.root, filepath.FromSlash(path.Clean("/"+name))))
+	if pe, ok := err.(*os.PathError); os.IsNotExist(err) || ok && pe.Err == syscall.ENOTDIR {
+		return nil, ErrFileNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	if fi, err := f.Stat(); err != nil {
+		f.Close()
+		return nil, err
+	} else if fi.IsDir() {
+		f.Close()
+		return nil, ErrFileNotFound
+	}
+
+	return f, nil
+}
+
+func (ref *archiveReference) Walk(dir string, fn func(name string) error) error {
+	return filepath.Walk(filepath.Join(ref.root, filepath.FromSlash(path.Clean("/"+dir))), func(name string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		} else if !fi.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(ref.root, name)
+		if err != nil {
+			return err
+		}
+
+		return fn(filepath.ToSlash(rel))
+	})
+}
+
+var archiveExtensions = []string{".tar.gz", ".tgz", ".tar.bz2", ".tbz2", ".tar", ".zip"}
+
+var versionRegexp = regexp.MustCompile(`v?(\d+(\.\d+)+[0-9A-Za-z.\-]*)$`)
+
+func archiveExtension(name string) string {
+	for _, ext := range archiveExtensions {
+		if strings.HasSuffix(strings.ToLower(name), ext) {
+			return ext
+		}
+	}
+
+	return ""
+}
+
+// versionFromFilename returns the version of a release archive, like
+// 2.3.4 for oscommerce-2.3.4.zip.
+func versionFromFilename(name string) string {
+	name = path.Base(name)
+	name = name[:len(name)-len(archiveExtension(name))]
+
+	if matches := versionRegexp.FindStringSubmatch(name); matches != nil {
+		return matches[1]
+	}
+
+	return name
+}
+
+func (s *archiveSource) Update() error {
+	archives := map[string]string{}
+	for version, location := range s.archives {
+		archives[version] = location
+	}
+
+	if len(archives) == 0 {
+		files, err := ioutil.ReadDir(s.repository)
+		if err != nil {
+			return err
+		}
+
+		for _, fi := range files {
+			if fi.IsDir() || archiveExtension(fi.Name()) == "" {
+				continue
+			}
+
+			archives[versionFromFilename(fi.Name())] = filepath.Join(s.repository, fi.Name())
+		}
+	}
+
+	if err := os.MkdirAll(s.cachePath, 0700); err != nil {
+		return err
+	}
+
+	versions := []string{}
+	for version := range archives {
+		versions = append(versions, version)
+	}
+
+	sort.Strings(versions)
+
+	fmt.Fprintln(s.out, color.YellowString("[+] Extracting archives to cache"))
+
+	s.refs = []Reference{}
+
+	for _, version := range versions {
+		root, err := s.extract(archives[version])
+		if err != nil {
+			fmt.Fprintln(s.out, color.RedString("[!] Could not extract archive %s: %s", archives[version], err.Error()))
+			continue
+		}
+
+		s.refs = append(s.refs, &archiveReference{
+			name: version,
+			root: root,
+		})
+	}
+
+	return nil
+}
+
+// extract extracts the archive at location into the cache, unless it has
+// been extracted before. It returns the root of the application in the
+// archive, skipping the single top level directory most archives have.
+func (s *archiveSource) extract(location string) (string, error) {
+	dest := filepath.Join(s.cachePath, hashStr(location))
+
+	if _, err := os.Stat(dest); err == nil {
+		return archiveRoot(dest)
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	src := location
+
+	if u, err := url.Parse(location); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		// the url doesn't need to end with the extension of the archive,
+		// the format is detected from the contents
+		src = dest + ".download"
+
+		fmt.Fprintf(s.out, " |  Downloading %s\n", location)
+
+		if err := download(s.fetch, location, src); err != nil {
+			return "", err
+		}
+
+		defer os.Remove(src)
+	}
+
+	tmp, err := ioutil.TempDir(s.cachePath, "extract")
+	if err != nil {
+		return "", err
+	}
+
+	defer os.RemoveAll(tmp)
+
+	if err := extractArchive(src, tmp); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(tmp, dest); err != nil {
+		return "", err
+	}
+
+	return archiveRoot(dest)
+}
+
+func archiveRoot(dest string) (string, error) {
+	files, err := ioutil.ReadDir(dest)
+	if err != nil {
+		return "", err
+	}
+
+	if len(files) == 1 && files[0].IsDir() {
+		return filepath.Join(dest, files[0].Name()), nil
+	}
+
+	return dest, nil
+}
+
+// extractPath returns the path to extract name to, rejecting names that
+// would escape dest.
+func extractPath(dest string, name string) (string, error) {
+	p := filepath.Join(dest, filepath.FromSlash(name))
+	if !strings.HasPrefix(p, filepath.Clean(dest)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("Invalid path in archive: %s", name)
+	}
+
+	return p, nil
+}
+
+func extractFile(p string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	_, err = io.Copy(f, r)
+	return err
+}
+
+// archiveFormat returns the extension of the format of the archive src,
+// detected from its first bytes or otherwise from its name.
+func archiveFormat(src string) (string, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+
+	defer f.Close()
+
+	header := make([]byte, 512)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")), bytes.HasPrefix(header, []byte("PK\x05\x06")):
+		return ".zip", nil
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		return ".tar.gz", nil
+	case bytes.HasPrefix(header, []byte("BZh")):
+		return ".tar.bz2", nil
+	case len(header) >= 262 && string(header[257:262]) == "ustar":
+		return ".tar", nil
+	}
+
+	return archiveExtension(src), nil
+}
+
+func extractArchive(src string, dest string) error {
+	format, err := archiveFormat(src)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case ".zip":
+		return extractZip(src, dest)
+	case ".tar":
+		return extractTar(src, dest, nil)
+	case ".tar.gz", ".tgz":
+		return extractTar(src, dest, func(r io.Reader) (io.Reader, error) {
+			return gzip.NewReader(r)
+		})
+	case ".tar.bz2", ".tbz2":
+		return extractTar(src, dest, func(r io.Reader) (io.Reader, error) {
+			return bzip2.NewReader(r), nil
+		})
+	default:
+		return fmt.Errorf("Unsupported archive: %s", src)
+	}
+}
+
+func extractZip(src string, dest string) error {
+	zr, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+
+	defer zr.Close()
+
+	for _, zf := range zr.File {
+		if zf.FileInfo().IsDir() {
+			continue
+		}
+
+		p, err := extractPath(dest, zf.Name)
+		if err != nil {
+			return err
+		}
+
+		r, err := zf.Open()
+		if err != nil {
+			return err
+		}
+
+		err = extractFile(p, r)
+		r.Close()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func extractTar(src string, dest string, decompress func(io.Reader) (io.Reader, error)) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	var r io.Reader = f
+	if decompress != nil {
+		if r, err = decompress(f); err != nil {
+			return err
+		}
+	}
+
+	tr := tar.NewReader(r)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		p, err := extractPath(dest, hdr.Name)
+		if err != nil {
+			return err
+		}
+
+		if err := extractFile(p, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *archiveSource) Branches() ([]Reference, error) {
+	return []Reference{}, nil
+}
+
+func (s *archiveSource) Tags() ([]Reference, error) {
+	return s.refs, nil
+}
@@ -0,0 +1,189 @@
+package app
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVersionFromFilename(t *testing.T) {
+	tests := map[string]string{
+		"oscommerce-2.3.4.zip":       "2.3.4",
+		"zen-cart-v1.5.5e.tar.gz":    "1.5.5e",
+		"/tmp/theme-1.0.0-beta2.tgz": "1.0.0-beta2",
+		"snapshot.zip":               "snapshot",
+	}
+
+	for name, expected := range tests {
+		if v := versionFromFilename(name); v != expected {
+			t.Errorf("versionFromFilename(%s): expected %s, got %s", name, expected, v)
+		}
+	}
+}
+
+func TestArchiveSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "identify")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	f, err := os.Create(filepath.Join(dir, "application-1.2.3.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zw := zip.NewWriter(f)
+	for name, content := range map[string]string{
+		"application-1.2.3/readme.txt":     "readme",
+		"application-1.2.3/css/style.css":  "body {}",
+		"application-1.2.3/../../evil.txt": "evil",
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w.Write([]byte(content))
+	}
+
+	zw.Close()
+	f.Close()
+
+	s := &archiveSource{
+		repository: dir,
+		cachePath:  filepath.Join(dir, "cache"),
+		out:        ioutil.Discard,
+	}
+
+	if err := s.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	refs, _ := s.Tags()
+	if len(refs) != 0 {
+		t.Fatalf("Expected archive with invalid path to be skipped, got %d references", len(refs))
+	}
+
+	os.Remove(filepath.Join(dir, "application-1.2.3.zip"))
+
+	f, err = os.Create(filepath.Join(dir, "application-1.2.4.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zw = zip.NewWriter(f)
+	w, _ := zw.Create("application-1.2.4/css/style.css")
+	w.Write([]byte("body {}"))
+	zw.Close()
+	f.Close()
+
+	if err := s.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	refs, _ = s.Tags()
+	if len(refs) != 1 {
+		t.Fatalf("Expected 1 reference, got %d", len(refs))
+	} else if refs[0].Name() != "1.2.4" {
+		t.Fatalf("Expected reference 1.2.4, got %s", refs[0].Name())
+	}
+
+	r, err := refs[0].Open("css/style.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer r.Close()
+
+	if data, _ := ioutil.ReadAll(r); string(data) != "body {}" {
+		t.Errorf("Unexpected content: %s", string(data))
+	}
+
+	for _, name := range []string{"css/missing.css", "css", "css/style.css/index.html"} {
+		if _, err := refs[0].Open(name); err != ErrFileNotFound {
+			t.Errorf("Expected %s not to be found, got %v", name, err)
+		}
+	}
+}
+
+func TestArchiveSourceDownload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "identify")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	writeZip(t, filepath.Join(dir, "application-1.0.0.zip"), map[string]string{
+		"application-1.0.0/readme.txt": "1.0.0",
+	})
+
+	// the format of archives without extension is detected
+	if err := ioutil.WriteFile(filepath.Join(dir, "download-3.0.0"), tarFiles(t, map[string]string{
+		"application-3.0.0/readme.txt": "3.0.0",
+	}, true), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// the archives can only be downloaded through the proxy
+	requests := 0
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.FileServer(http.Dir(dir)).ServeHTTP(w, r)
+	}))
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+
+	b := newTestIdentify(t, dir, &Application{
+		Name: "application",
+		Type: SourceArchive,
+		Archives: map[string]string{
+			"1.0.0": "http://archives.invalid/application-1.0.0.zip",
+			"2.0.0": "http://archives.invalid/application-2.0.0.zip",
+			"3.0.0": "http://archives.invalid/download-3.0.0",
+		},
+	})
+
+	b.transport = &http.Transport{Proxy: http.ProxyURL(proxyURL)}
+
+	// archives aren't requested through the transport of the target
+	b.client.Transport = &fileTransport{root: dir}
+
+	source, release, err := b.openSource()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer release()
+
+	if err := source.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	refs, _ := source.Tags()
+	if len(refs) != 2 || refs[0].Name() != "1.0.0" || refs[1].Name() != "3.0.0" {
+		t.Fatalf("Expected only the archives that could be downloaded, got %d references", len(refs))
+	} else if requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", requests)
+	}
+
+	r, err := refs[1].Open("readme.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer r.Close()
+
+	if data, _ := ioutil.ReadAll(r); string(data) != "3.0.0" {
+		t.Errorf("Unexpected content: %s", string(data))
+	}
+}
@@ -0,0 +1,168 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/storer"
+	"gopkg.in/src-d/go-git.v4/storage/filesystem"
+
+	"gopkg.in/src-d/go-billy.v2/osfs"
+)
+
+type gitSource struct {
+	repository string
+	cachePath  string
+
+	out io.Writer
+
+	r *git.Repository
+}
+
+type gitReference struct {
+	name   string
+	commit *object.Commit
+	tree   *object.Tree
+}
+
+func normalize(name plumbing.ReferenceName) string {
+	s := name.String()
+	s = strings.Replace(s, "refs/tags/", "", -1)
+	s = strings.Replace(s, "refs/heads/", "", -1)
+	return s
+}
+
+func (ref *gitReference) Name() string {
+	return ref.name
+}
+
+func (ref *gitReference) Open(name string) (io.ReadCloser, error) {
+	f, err := ref.tree.File(name)
+	if err == object.ErrFileNotFound {
+		return nil, ErrFileNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return f.Reader()
+}
+
+func (ref *gitReference) Walk(dir string, fn func(name string) error) error {
+	if ref.tree == nil {
+		return fmt.Errorf("Reference %s has no tree", ref.name)
+	}
+
+	return ref.tree.Files().ForEach(func(f *object.File) error {
+		if !inDir(f.Name, dir) {
+			return nil
+		}
+
+		return fn(f.Name)
+	})
+}
+
+func (s *gitSource) Update() error {
+	storage, err := filesystem.NewStorage(osfs.New(s.cachePath))
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(s.out, color.YellowString("[+] Cloning repository to cache"))
+
+	r, err := git.Open(storage, nil)
+	if err == nil {
+	} else if err.Error() != "repository not exists" {
+		// unknown open error
+		return err
+	} else if r, err = git.Clone(storage, nil, &git.CloneOptions{
+		URL:      s.repository,
+		Progress: s.out,
+	}); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(s.out, color.YellowString("[+] Pulling latest changes from remote repository"))
+
+	err = r.Fetch(&git.FetchOptions{
+		Progress: s.out,
+	})
+	if err == nil {
+	} else if err.Error() == "already up-to-date" {
+		fmt.Fprintln(s.out, " |  Repository already up-to-date")
+	} else {
+		return err
+	}
+
+	s.r = r
+	return nil
+}
+
+// Revision returns the commit of HEAD of the cached repository.
+func (s *gitSource) Revision() (string, error) {
+	if s.r == nil {
+		return "", fmt.Errorf("Repository hasn't been updated")
+	}
+
+	ref, err := s.r.Head()
+	if err != nil {
+		return "", err
+	}
+
+	return ref.Hash().String(), nil
+}
+
+func (s *gitSource) references(ri storer.ReferenceIter) ([]Reference, error) {
+	refs := []Reference{}
+
+	err := ri.ForEach(func(ref *plumbing.Reference) error {
+		var commit *object.Commit
+		var tree *object.Tree
+		if c, err := s.r.CommitObject(ref.Hash()); err == nil {
+			commit = c
+			tree, _ = c.Tree()
+		} else if t, err := s.r.TagObject(ref.Hash()); err == nil {
+			commit, _ = t.Commit()
+			tree, _ = t.Tree()
+		} else if err != nil {
+			fmt.Fprintln(s.out, color.RedString("Could not find commit or tag for %s: %s %s", ref.Name(), ref.Hash().String(), err.Error()))
+			return nil
+		}
+
+		if tree == nil {
+			return fmt.Errorf("Could not find tree for commit or tag")
+		}
+
+		refs = append(refs, &gitReference{
+			name:   normalize(ref.Name()),
+			commit: commit,
+			tree:   tree,
+		})
+
+		return nil
+	})
+
+	return refs, err
+}
+
+func (s *gitSource) Branches() ([]Reference, error) {
+	ri, err := s.r.Branches()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.references(ri)
+}
+
+func (s *gitSource) Tags() ([]Reference, error) {
+	ri, err := s.r.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.references(ri)
+}
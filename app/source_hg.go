@@ -0,0 +1,167 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// hgSource retrieves versions from a Mercurial repository, using the hg
+// command.
+type hgSource struct {
+	repository string
+	cachePath  string
+
+	out io.Writer
+}
+
+type hgReference struct {
+	s        *hgSource
+	name     string
+	revision string
+}
+
+func (ref *hgReference) Name() string {
+	return ref.name
+}
+
+func (ref *hgReference) Open(name string) (io.ReadCloser, error) {
+	// a path: pattern is relative to the repository root, a plain name would
+	// be relative to the working directory
+	data, err := ref.s.hg("cat", "-r", ref.revision, "path:"+strings.TrimPrefix(name, "/"))
+	if ce, ok := err.(*commandError); ok && ce.exitCode() == 1 {
+		// hg cat exits with 1 when no file matches, and 255 on errors
+		return nil, ErrFileNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (ref *hgReference) Walk(dir string, fn func(name string) error) error {
+	data, err := ref.s.hg("manifest", "-r", ref.revision)
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if name := scanner.Text(); name == "" || !inDir(name, dir) {
+		} else if err := fn(name); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (s *hgSource) hg(args ...string) ([]byte, error) {
+	stderr := &bytes.Buffer{}
+
+	cmd := exec.Command("hg", append([]string{"-R", s.cachePath}, args...)...)
+	cmd.Stderr = stderr
+
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, &commandError{
+			command: "hg " + args[0],
+			err:     err,
+			stderr:  strings.TrimSpace(stderr.String()),
+		}
+	}
+
+	return data, nil
+}
+
+// Revision returns the changeset of the tip of the cached repository.
+func (s *hgSource) Revision() (string, error) {
+	data, err := s.hg("log", "-r", "tip", "--template", "{node}")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (s *hgSource) Update() error {
+	if _, err := os.Stat(s.cachePath); err == nil {
+	} else if !os.IsNotExist(err) {
+		return err
+	} else {
+		fmt.Fprintln(s.out, color.YellowString("[+] Cloning repository to cache"))
+
+		cmd := exec.Command("hg", "clone", "--noupdate", s.repository, s.cachePath)
+		cmd.Stdout = s.out
+		cmd.Stderr = s.out
+
+		return cmd.Run()
+	}
+
+	fmt.Fprintln(s.out, color.YellowString("[+] Pulling latest changes from remote repository"))
+
+	_, err := s.hg("pull")
+	return err
+}
+
+// references parses the output of hg tags and hg branches, which consists of
+// lines with the name followed by rev:node.
+func (s *hgSource) references(command string) ([]Reference, error) {
+	data, err := s.hg(command)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := []Reference{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		// names can contain spaces, the revision is the last field
+		// containing a colon
+		i := len(fields) - 1
+		for ; i > 0; i-- {
+			if strings.Contains(fields[i], ":") {
+				break
+			}
+		}
+
+		if i == 0 {
+			continue
+		}
+
+		name := strings.Join(fields[:i], " ")
+		if name == "tip" {
+			continue
+		}
+
+		parts := strings.SplitN(fields[i], ":", 2)
+
+		refs = append(refs, &hgReference{
+			s:        s,
+			name:     name,
+			revision: parts[1],
+		})
+	}
+
+	return refs, scanner.Err()
+}
+
+func (s *hgSource) Branches() ([]Reference, error) {
+	return s.references("branches")
+}
+
+func (s *hgSource) Tags() ([]Reference, error) {
+	return s.references("tags")
+}
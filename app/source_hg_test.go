@@ -0,0 +1,108 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// runCommand runs the command name in dir, failing the test with its output
+// when it fails.
+func runCommand(t *testing.T, dir string, name string, args ...string) {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+
+	if data, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%s %v: %s: %s", name, args, err.Error(), string(data))
+	}
+}
+
+// readReference returns the contents of the file name in ref.
+func readReference(t *testing.T, ref Reference, name string) string {
+	r, err := ref.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer r.Close()
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func TestHgSource(t *testing.T) {
+	if _, err := exec.LookPath("hg"); err != nil {
+		t.Skip("hg is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "identify")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	repository := filepath.Join(dir, "repository")
+
+	writeFiles(t, repository, map[string]string{
+		"catalog/readme.txt":     "1.0",
+		"catalog/css/style.css":  "body {}",
+		"catalog/images/bg.html": "<html></html>",
+	})
+
+	runCommand(t, repository, "hg", "init")
+	runCommand(t, repository, "hg", "--config", "ui.username=identify", "commit", "--addremove", "-m", "1.0")
+	runCommand(t, repository, "hg", "--config", "ui.username=identify", "tag", "1.0")
+
+	s := &hgSource{
+		repository: repository,
+		cachePath:  filepath.Join(dir, "cache"),
+		out:        ioutil.Discard,
+	}
+
+	// clones the first time, pulls the second
+	for i := 0; i < 2; i++ {
+		if err := s.Update(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if revision, err := s.Revision(); err != nil {
+		t.Fatal(err)
+	} else if revision == "" {
+		t.Error("Expected the revision of the tip")
+	}
+
+	tags, err := s.Tags()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(tags) != 1 || tags[0].Name() != "1.0" {
+		t.Fatalf("Expected tag 1.0, got %d tags", len(tags))
+	}
+
+	// files are relative to the root of the repository, not to the working
+	// directory
+	if content := readReference(t, tags[0], "catalog/css/style.css"); content != "body {}" {
+		t.Errorf("Unexpected content: %s", content)
+	}
+
+	if _, err := tags[0].Open("catalog/missing.txt"); err != ErrFileNotFound {
+		t.Errorf("Expected missing file not to be found, got %v", err)
+	}
+
+	names := []string{}
+	if err := tags[0].(Walker).Walk("catalog/css", func(name string) error {
+		names = append(names, name)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	} else if len(names) != 1 || names[0] != "catalog/css/style.css" {
+		t.Errorf("Expected only catalog/css/style.css, got %v", names)
+	}
+}
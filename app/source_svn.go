@@ -0,0 +1,162 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+)
+
+// svnSource retrieves versions from a Subversion repository with the
+// standard trunk, branches and tags layout, using the svn command. Files are
+// retrieved from the remote repository, there is no local cache.
+type svnSource struct {
+	repository string
+}
+
+type svnReference struct {
+	s    *svnSource
+	name string
+	url  string
+}
+
+func (ref *svnReference) Name() string {
+	return ref.name
+}
+
+func (ref *svnReference) Open(name string) (io.ReadCloser, error) {
+	data, err := ref.s.svn("cat", ref.url+"/"+name)
+	if ce, ok := err.(*commandError); ok && svnNotFound(ce.stderr) {
+		return nil, ErrFileNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *svnSource) svn(args ...string) ([]byte, error) {
+	stderr := &bytes.Buffer{}
+
+	cmd := exec.Command("svn", append([]string{"--non-interactive"}, args...)...)
+	cmd.Stderr = stderr
+
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, &commandError{
+			command: "svn " + args[0],
+			err:     err,
+			stderr:  strings.TrimSpace(stderr.String()),
+		}
+	}
+
+	return data, nil
+}
+
+// svnNotFoundCodes are the error codes of svn cat for paths that don't exist
+// or aren't files.
+var svnNotFoundCodes = []string{"W160013", "E160013", "E170000", "E195012", "E200009"}
+
+// svnNotFound returns true if the stderr of svn reports a path that doesn't
+// exist.
+func svnNotFound(stderr string) bool {
+	for _, code := range svnNotFoundCodes {
+		if strings.Contains(stderr, code+":") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Revision returns the latest revision of the repository.
+func (s *svnSource) Revision() (string, error) {
+	data, err := s.svn("info", "--show-item", "revision", s.repository)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (s *svnSource) Update() error {
+	return nil
+}
+
+func (ref *svnReference) Walk(dir string, fn func(name string) error) error {
+	dir = strings.Trim(dir, "/")
+
+	base := ref.url
+	if dir != "" {
+		base += "/" + dir
+	}
+
+	data, err := ref.s.svn("ls", "-R", base)
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		if name == "" || strings.HasSuffix(name, "/") {
+			continue
+		}
+
+		if dir != "" {
+			name = dir + "/" + name
+		}
+
+		if err := fn(name); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (s *svnSource) list(dir string) ([]Reference, error) {
+	base := strings.TrimSuffix(s.repository, "/") + "/" + dir
+
+	data, err := s.svn("ls", base)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := []Reference{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		name := strings.TrimSuffix(strings.TrimSpace(scanner.Text()), "/")
+		if name == "" {
+			continue
+		}
+
+		refs = append(refs, &svnReference{
+			s:    s,
+			name: name,
+			url:  base + "/" + name,
+		})
+	}
+
+	return refs, scanner.Err()
+}
+
+func (s *svnSource) Branches() ([]Reference, error) {
+	refs, err := s.list("branches")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(refs, &svnReference{
+		s:    s,
+		name: "trunk",
+		url:  strings.TrimSuffix(s.repository, "/") + "/trunk",
+	}), nil
+}
+
+func (s *svnSource) Tags() ([]Reference, error) {
+	return s.list("tags")
+}
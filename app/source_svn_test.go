@@ -0,0 +1,86 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestSvnSource(t *testing.T) {
+	for _, name := range []string{"svn", "svnadmin"} {
+		if _, err := exec.LookPath(name); err != nil {
+			t.Skipf("%s is not installed", name)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "identify")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	runCommand(t, dir, "svnadmin", "create", "repository")
+
+	repository := "file://" + filepath.ToSlash(filepath.Join(dir, "repository"))
+
+	layout := filepath.Join(dir, "layout")
+
+	writeFiles(t, layout, map[string]string{
+		"trunk/readme.txt":    "1.0",
+		"trunk/css/style.css": "body {}",
+	})
+
+	for _, name := range []string{"branches", "tags"} {
+		if err := os.MkdirAll(filepath.Join(layout, name), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runCommand(t, dir, "svn", "--non-interactive", "import", "-m", "layout", layout, repository)
+	runCommand(t, dir, "svn", "--non-interactive", "copy", "-m", "1.0", repository+"/trunk", repository+"/tags/1.0")
+
+	s := &svnSource{
+		repository: repository,
+	}
+
+	if revision, err := s.Revision(); err != nil {
+		t.Fatal(err)
+	} else if revision != "2" {
+		t.Errorf("Expected revision 2, got %s", revision)
+	}
+
+	branches, err := s.Branches()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(branches) != 1 || branches[0].Name() != "trunk" {
+		t.Errorf("Expected only trunk, got %d branches", len(branches))
+	}
+
+	tags, err := s.Tags()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(tags) != 1 || tags[0].Name() != "1.0" {
+		t.Fatalf("Expected tag 1.0, got %d tags", len(tags))
+	}
+
+	if content := readReference(t, tags[0], "css/style.css"); content != "body {}" {
+		t.Errorf("Unexpected content: %s", content)
+	}
+
+	if _, err := tags[0].Open("missing.txt"); err != ErrFileNotFound {
+		t.Errorf("Expected missing file not to be found, got %v", err)
+	}
+
+	names := []string{}
+	if err := tags[0].(Walker).Walk("css", func(name string) error {
+		names = append(names, name)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	} else if len(names) != 1 || names[0] != "css/style.css" {
+		t.Errorf("Expected only css/style.css, got %v", names)
+	}
+}
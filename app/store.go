@@ -0,0 +1,307 @@
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Target is a target registered for monitoring.
+type Target struct {
+	URL         string        `json:"url"`
+	Application string        `json:"application"`
+	Interval    time.Duration `json:"interval"`
+	Added       time.Time     `json:"added"`
+	LastScan    time.Time     `json:"last_scan"`
+}
+
+// Due returns true if the target should be scanned at now.
+func (t *Target) Due(now time.Time) bool {
+	return t.LastScan.IsZero() || !now.Before(t.LastScan.Add(t.Interval))
+}
+
+// Store persists the monitored targets, with the history of their scans and
+// events, as json files in a directory.
+type Store struct {
+	path string
+
+	m sync.Mutex
+}
+
+// OpenStore opens the store in path, creating the directory if it doesn't
+// exist.
+func OpenStore(path string) (*Store, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, err
+	}
+
+	return &Store{
+		path: path,
+	}, nil
+}
+
+func (s *Store) targetPath(url string) string {
+	return filepath.Join(s.path, hashStr(url))
+}
+
+func (s *Store) targets() (map[string]*Target, error) {
+	targets := map[string]*Target{}
+
+	data, err := ioutil.ReadFile(filepath.Join(s.path, "targets.json"))
+	if os.IsNotExist(err) {
+		return targets, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &targets); err != nil {
+		return nil, err
+	}
+
+	return targets, nil
+}
+
+func (s *Store) writeTargets(targets map[string]*Target) error {
+	data, err := json.MarshalIndent(targets, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	name := filepath.Join(s.path, "targets.json")
+	if err := ioutil.WriteFile(name+".tmp", data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(name+".tmp", name)
+}
+
+// Targets returns the registered targets, sorted by url.
+func (s *Store) Targets() ([]*Target, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	targets, err := s.targets()
+	if err != nil {
+		return nil, err
+	}
+
+	list := []*Target{}
+	for _, target := range targets {
+		list = append(list, target)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].URL < list[j].URL
+	})
+
+	return list, nil
+}
+
+// SaveTarget registers the target, replacing the target with the same url.
+func (s *Store) SaveTarget(target *Target) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	targets, err := s.targets()
+	if err != nil {
+		return err
+	}
+
+	targets[target.URL] = target
+	return s.writeTargets(targets)
+}
+
+// RemoveTarget removes the target with url, its history is kept.
+func (s *Store) RemoveTarget(url string) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	targets, err := s.targets()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := targets[url]; !ok {
+		return fmt.Errorf("Target not found: %s", url)
+	}
+
+	delete(targets, url)
+	return s.writeTargets(targets)
+}
+
+// SetLastScan sets the time of the last scan of the target with url, if it
+// is still registered.
+func (s *Store) SetLastScan(url string, t time.Time) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	targets, err := s.targets()
+	if err != nil {
+		return err
+	}
+
+	target, ok := targets[url]
+	if !ok {
+		return nil
+	}
+
+	target.LastScan = t
+	return s.writeTargets(targets)
+}
+
+// appendJSON appends v as a line of json to the file name of the target.
+func (s *Store) appendJSON(url string, name string, v interface{}) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if err := os.MkdirAll(s.targetPath(url), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Join(s.targetPath(url), name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// readJSON calls fn with every line of json of the file name of the target.
+func (s *Store) readJSON(url string, name string, fn func(data []byte) error) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	f, err := os.Open(filepath.Join(s.targetPath(url), name))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	for scanner.Scan() {
+		if err := fn(scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// AddScan adds the scan to the history of the target with url, and keeps
+// it as the last identified scan when a version has been identified.
+func (s *Store) AddScan(url string, scan *Scan) error {
+	if err := s.appendJSON(url, "scans.json", scan); err != nil {
+		return err
+	} else if scan.Version == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(scan)
+	if err != nil {
+		return err
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	name := filepath.Join(s.targetPath(url), "identified.json")
+	if err := ioutil.WriteFile(name+".tmp", data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(name+".tmp", name)
+}
+
+// LastIdentifiedScan returns the last scan of the target with url that
+// identified a version, or nil if there is none.
+func (s *Store) LastIdentifiedScan(url string) (*Scan, error) {
+	s.m.Lock()
+	data, err := ioutil.ReadFile(filepath.Join(s.targetPath(url), "identified.json"))
+	s.m.Unlock()
+
+	if err == nil {
+		scan := &Scan{}
+		if err := json.Unmarshal(data, scan); err != nil {
+			return nil, err
+		}
+
+		return scan, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	// histories written before the last identified scan was kept
+	var last *Scan
+
+	err = s.readJSON(url, "scans.json", func(data []byte) error {
+		scan := &Scan{}
+		if err := json.Unmarshal(data, scan); err != nil {
+			return err
+		} else if scan.Version != "" {
+			last = scan
+		}
+
+		return nil
+	})
+
+	return last, err
+}
+
+// Scans returns the history of scans of the target with url, the oldest
+// first.
+func (s *Store) Scans(url string) ([]*Scan, error) {
+	scans := []*Scan{}
+
+	err := s.readJSON(url, "scans.json", func(data []byte) error {
+		scan := &Scan{}
+		if err := json.Unmarshal(data, scan); err != nil {
+			return err
+		}
+
+		scans = append(scans, scan)
+		return nil
+	})
+
+	return scans, err
+}
+
+// AddEvent adds the event to the events of its target.
+func (s *Store) AddEvent(event *Event) error {
+	return s.appendJSON(event.URL, "events.json", event)
+}
+
+// Events returns the events of the target with url, the oldest first.
+func (s *Store) Events(url string) ([]*Event, error) {
+	events := []*Event{}
+
+	err := s.readJSON(url, "events.json", func(data []byte) error {
+		event := &Event{}
+		if err := json.Unmarshal(data, event); err != nil {
+			return err
+		}
+
+		events = append(events, event)
+		return nil
+	})
+
+	return events, err
+}
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// templateFuncs are the helper functions available in report templates.
+var templateFuncs = map[string]interface{}{
+	"percent": func(f float64) string {
+		return fmt.Sprintf("%.0f%%", f*100)
+	},
+	"join": strings.Join,
+	"date": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+
+		return t.Format(time.RFC3339)
+	},
+	"now": time.Now,
+	"md": func(s string) string {
+		return strings.NewReplacer("|", "\\|", "\n", " ", "*", "\\*", "_", "\\_", "`", "\\`").Replace(s)
+	},
+	// versions returns the names of the versions
+	"versions": func(versions []*VersionReport) []string {
+		names := []string{}
+		for _, vr := range versions {
+			names = append(names, vr.Version)
+		}
+
+		return names
+	},
+	// candidates returns the names of the best matching versions
+	"candidates": func(r *Report) []string {
+		names := []string{}
+		for _, vr := range r.Versions {
+			if vr.Matches != r.Versions[0].Matches {
+				break
+			}
+
+			names = append(names, vr.Version)
+		}
+
+		return names
+	},
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// WriteTemplate renders the report with the text/template text to w. A
+// newline is added when the template doesn't end with one.
+func (r *Report) WriteTemplate(w io.Writer, text string) error {
+	t, err := template.New("report").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return err
+	}
+
+	if err := t.Execute(w, r); err != nil {
+		return err
+	}
+
+	if !strings.HasSuffix(text, "\n") {
+		_, err = io.WriteString(w, "\n")
+	}
+
+	return err
+}
@@ -2,6 +2,11 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/minio/cli"
@@ -38,6 +43,39 @@ var globalFlags = []cli.Flag{
 		Usage: "the application to identify",
 		Value: "",
 	},
+	cli.StringFlag{
+		Name:  "path",
+		Usage: "identify the application installed in a local directory",
+		Value: "",
+	},
+	cli.StringFlag{
+		Name:  "image",
+		Usage: "identify the application in a docker save tarball or OCI layout directory",
+		Value: "",
+	},
+	cli.StringFlag{
+		Name:  "capture",
+		Usage: "identify passively from a HAR file, WARC file or wget mirror, relative to the target url",
+		Value: "",
+	},
+	cli.StringFlag{
+		Name:  "evidence",
+		Usage: "store the raw responses in this directory, to reanalyze them later",
+		Value: "",
+	},
+	cli.StringFlag{
+		Name:  "bundle",
+		Usage: "write an evidence bundle with the responses, checksums and result to this file",
+		Value: "",
+	},
+	cli.BoolFlag{
+		Name:  "all-files",
+		Usage: "compare all files of a local path with the identified version",
+	},
+	cli.BoolFlag{
+		Name:  "integrity",
+		Usage: "report files that are modified or missing compared to the identified version",
+	},
 	cli.StringFlag{
 		Name:  "proxy",
 		Usage: "socks5://127.0.0.1:9050",
@@ -56,9 +94,106 @@ var globalFlags = []cli.Flag{
 		Name:  "no-tags",
 		Usage: "don't identify tags",
 	},
+	cli.BoolFlag{
+		Name:  "no-soft404",
+		Usage: "don't detect soft 404 pages",
+	},
+	cli.StringFlag{
+		Name:  "redirects",
+		Usage: "redirect policy: follow, same-host or never",
+		Value: "same-host",
+	},
+	cli.IntFlag{
+		Name:  "max-redirects",
+		Usage: "maximum number of redirects to follow",
+		Value: 10,
+	},
+	cli.DurationFlag{
+		Name:  "timeout",
+		Usage: "timeout per request",
+		Value: 30 * time.Second,
+	},
+	cli.DurationFlag{
+		Name:  "overall-timeout",
+		Usage: "timeout for all remote requests, 0 to disable",
+		Value: 0,
+	},
+	cli.IntFlag{
+		Name:  "retries",
+		Usage: "number of retries on network errors, 429 and 503",
+		Value: 2,
+	},
+	cli.DurationFlag{
+		Name:  "backoff",
+		Usage: "initial delay between retries",
+		Value: time.Second,
+	},
+	cli.Int64Flag{
+		Name:  "max-body-size",
+		Usage: "maximum size in bytes of a remote file",
+		Value: 10 * 1024 * 1024,
+	},
+	cli.BoolFlag{
+		Name:  "commits",
+		Usage: "identify the commit between the best matching tags",
+	},
+	cli.IntFlag{
+		Name:  "max-commits",
+		Usage: "maximum number of commits to search",
+		Value: 1000,
+	},
+	cli.BoolFlag{
+		Name:  "components",
+		Usage: "identify plugins, extensions and themes",
+	},
+	cli.StringFlag{
+		Name:  "slugs",
+		Usage: "comma separated list of components to identify as kind:slug, like plugin:akismet, instead of the rule set",
+		Value: "",
+	},
+	cli.StringFlag{
+		Name:  "slugs-file",
+		Usage: "file with components to identify as kind:slug, one per line",
+		Value: "",
+	},
+	cli.StringFlag{
+		Name:  "advisories",
+		Usage: "advisory file or directory (OSV json or yaml)",
+		Value: "",
+	},
 	cli.BoolFlag{
 		Name:  "json",
-		Usage: "output json",
+		Usage: "output json, same as --format json",
+	},
+	cli.StringFlag{
+		Name:  "format",
+		Usage: "report format: json, cyclonedx, sarif, html, markdown or a template like '{{.Target}} {{.Best.Version}}'",
+		Value: "",
+	},
+	cli.StringFlag{
+		Name:  "template",
+		Usage: "file with a text/template to render the report with",
+		Value: "",
+	},
+	cli.StringFlag{
+		Name:  "output",
+		Usage: "write the report to file instead of stdout",
+		Value: "",
+	},
+	cli.StringSliceFlag{
+		Name:  "webhook",
+		Usage: "url to post notifications to, can be repeated",
+		Value: &cli.StringSlice{},
+	},
+	cli.StringSliceFlag{
+		Name:  "hook-command",
+		Usage: "command to pass notifications to on stdin, can be repeated",
+		Value: &cli.StringSlice{},
+	},
+	cli.StringFlag{
+		Name:  "hook-events",
+		Usage: "comma separated events to notify of: scan-completed, advisories, version-changed, version-regressed, inconsistent-evidence",
+		Value: "",
 	},
 }
 
@@ -70,6 +205,243 @@ func VersionAction(c *cli.Context) {
 	fmt.Println(color.YellowString(fmt.Sprintf("identify")))
 }
 
+func readSlugs(s string, filename string) ([]string, error) {
+	slugs := []string{}
+
+	for _, slug := range strings.Split(s, ",") {
+		if slug = strings.TrimSpace(slug); slug != "" {
+			slugs = append(slugs, slug)
+		}
+	}
+
+	if filename == "" {
+		return slugs, nil
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, slug := range strings.Split(string(data), "\n") {
+		if slug = strings.TrimSpace(slug); slug != "" && !strings.HasPrefix(slug, "#") {
+			slugs = append(slugs, slug)
+		}
+	}
+
+	return slugs, nil
+}
+
+// reportWriterFn writes the report to w.
+type reportWriterFn func(w io.Writer, report *identify.Report) error
+
+func writeReport(report *identify.Report, write reportWriterFn, output string) error {
+	if output == "" {
+		return write(os.Stdout, report)
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	return write(f, report)
+}
+
+// reportWriter returns the writer of the report in the format, or executing
+// the template when set. It returns nil when no report has been asked for.
+func reportWriter(c *cli.Context) (reportWriterFn, error) {
+	if filename := c.GlobalString("template"); filename == "" {
+	} else if data, err := ioutil.ReadFile(filename); err != nil {
+		return nil, fmt.Errorf("Could not read template: %s", err.Error())
+	} else {
+		return func(w io.Writer, report *identify.Report) error {
+			return report.WriteTemplate(w, string(data))
+		}, nil
+	}
+
+	format := c.GlobalString("format")
+	if format == "" && c.GlobalBool("json") {
+		format = "json"
+	}
+
+	if format == "" {
+		return nil, nil
+	}
+
+	return func(w io.Writer, report *identify.Report) error {
+		return report.Write(w, format)
+	}, nil
+}
+
+// globalOptions returns the identification options of the global flags,
+// except for the target url, application and output.
+func globalOptions(c *cli.Context) ([]identify.OptionFn, error) {
+	options := []identify.OptionFn{}
+
+	if fn, err := identify.ToolVersion(Version); err != nil {
+	} else {
+		options = append(options, fn)
+	}
+
+	if proxy := c.GlobalString("proxy"); proxy == "" {
+	} else if fn, err := identify.ProxyURL(proxy); err != nil {
+		return nil, fmt.Errorf("Could not set proxy: %s", err.Error())
+	} else {
+		options = append(options, fn)
+	}
+
+	if !c.GlobalBool("no-branches") {
+	} else if fn, err := identify.NoBranches(); err != nil {
+	} else {
+		options = append(options, fn)
+	}
+
+	if !c.GlobalBool("no-tags") {
+	} else if fn, err := identify.NoTags(); err != nil {
+	} else {
+		options = append(options, fn)
+	}
+
+	if !c.GlobalBool("no-soft404") {
+	} else if fn, err := identify.NoSoft404(); err != nil {
+	} else {
+		options = append(options, fn)
+	}
+
+	if fn, err := identify.RedirectPolicy(c.GlobalString("redirects")); err != nil {
+		return nil, fmt.Errorf("Could not set redirect policy: %s", err.Error())
+	} else {
+		options = append(options, fn)
+	}
+
+	if fn, err := identify.MaxRedirects(c.GlobalInt("max-redirects")); err != nil {
+		return nil, fmt.Errorf("Could not set maximum redirects: %s", err.Error())
+	} else {
+		options = append(options, fn)
+	}
+
+	if fn, err := identify.Timeout(c.GlobalDuration("timeout")); err != nil {
+		return nil, fmt.Errorf("Could not set timeout: %s", err.Error())
+	} else {
+		options = append(options, fn)
+	}
+
+	if fn, err := identify.OverallTimeout(c.GlobalDuration("overall-timeout")); err != nil {
+		return nil, fmt.Errorf("Could not set overall timeout: %s", err.Error())
+	} else {
+		options = append(options, fn)
+	}
+
+	if fn, err := identify.Retries(c.GlobalInt("retries")); err != nil {
+		return nil, fmt.Errorf("Could not set retries: %s", err.Error())
+	} else {
+		options = append(options, fn)
+	}
+
+	if fn, err := identify.Backoff(c.GlobalDuration("backoff")); err != nil {
+		return nil, fmt.Errorf("Could not set backoff: %s", err.Error())
+	} else {
+		options = append(options, fn)
+	}
+
+	if fn, err := identify.MaxBodySize(c.GlobalInt64("max-body-size")); err != nil {
+		return nil, fmt.Errorf("Could not set maximum body size: %s", err.Error())
+	} else {
+		options = append(options, fn)
+	}
+
+	if !c.GlobalBool("commits") {
+	} else if fn, err := identify.Commits(); err != nil {
+	} else {
+		options = append(options, fn)
+	}
+
+	if fn, err := identify.MaxCommits(c.GlobalInt("max-commits")); err != nil {
+		return nil, fmt.Errorf("Could not set maximum commits: %s", err.Error())
+	} else {
+		options = append(options, fn)
+	}
+
+	if !c.GlobalBool("components") {
+	} else if slugs, err := readSlugs(c.GlobalString("slugs"), c.GlobalString("slugs-file")); err != nil {
+		return nil, fmt.Errorf("Could not read slugs: %s", err.Error())
+	} else if fn, err := identify.Components(slugs); err != nil {
+		return nil, fmt.Errorf("Could not set slugs: %s", err.Error())
+	} else {
+		options = append(options, fn)
+	}
+
+	if advisories := c.GlobalString("advisories"); advisories == "" {
+	} else if fn, err := identify.AdvisoriesPath(advisories); err != nil {
+		return nil, fmt.Errorf("Could not load advisories: %s", err.Error())
+	} else {
+		options = append(options, fn)
+	}
+
+	if !c.GlobalBool("all-files") {
+	} else if fn, err := identify.AllFiles(); err != nil {
+	} else {
+		options = append(options, fn)
+	}
+
+	if !c.GlobalBool("integrity") {
+	} else if fn, err := identify.Integrity(); err != nil {
+	} else {
+		options = append(options, fn)
+	}
+
+	if !c.GlobalBool("debug") {
+	} else if fn, err := identify.Debug(); err != nil {
+	} else {
+		options = append(options, fn)
+	}
+
+	if evidence := c.GlobalString("evidence"); evidence == "" {
+	} else if fn, err := identify.StoreEvidence(evidence); err != nil {
+		return nil, err
+	} else {
+		options = append(options, fn)
+	}
+
+	if hooks, err := globalHooks(c); err != nil {
+		return nil, err
+	} else if hooks == nil {
+	} else if fn, err := identify.Notify(hooks); err != nil {
+	} else {
+		options = append(options, fn)
+	}
+
+	return options, nil
+}
+
+// globalHooks returns the hooks of the global flags, or nil if no webhooks
+// or commands have been set.
+func globalHooks(c *cli.Context) (*identify.Hooks, error) {
+	webhooks := c.GlobalStringSlice("webhook")
+	commands := c.GlobalStringSlice("hook-command")
+
+	if len(webhooks) == 0 && len(commands) == 0 {
+		return nil, nil
+	}
+
+	events := []string{}
+	for _, event := range strings.Split(c.GlobalString("hook-events"), ",") {
+		if event = strings.TrimSpace(event); event != "" {
+			events = append(events, event)
+		}
+	}
+
+	hooks, err := identify.NewHooks(webhooks, commands, events)
+	if err != nil {
+		return nil, fmt.Errorf("Could not set hooks: %s", err.Error())
+	}
+
+	return hooks, nil
+}
+
 func New() *Cmd {
 	app := cli.NewApp()
 	app.Name = "identify"
@@ -83,80 +455,148 @@ func New() *Cmd {
 			Name:   "version",
 			Action: VersionAction,
 		},
+		{
+			Name:   "serve",
+			Usage:  "expose identification as a REST API",
+			Flags:  serveFlags,
+			Action: ServeAction,
+		},
+		{
+			Name:   "plan",
+			Usage:  "list the urls that would be requested, without requesting them",
+			Flags:  planFlags,
+			Action: PlanAction,
+		},
+		{
+			Name:   "ingest",
+			Usage:  "identify from a directory of fetched responses",
+			Flags:  planFlags,
+			Action: IngestAction,
+		},
+		{
+			Name:   "reanalyze",
+			Usage:  "identify again from the stored responses, with the current database",
+			Flags:  planFlags,
+			Action: ReanalyzeAction,
+		},
+		monitorCommand,
 	}
 
 	app.Before = func(c *cli.Context) error {
 		return nil
 	}
 
-	app.Action = func(c *cli.Context) {
-		fmt.Println("Identify - Identify web application versions")
-		fmt.Println("http://github.com/dutchcoders/identify")
-		fmt.Println("")
-		fmt.Println("DutchSec [https://dutchsec.com/]")
-		fmt.Println("--------------------------------------")
+	app.Action = IdentifyAction
 
-		options := []identify.OptionFn{}
+	return &Cmd{
+		App: app,
+	}
+}
 
-		if args := c.Args(); len(args) == 0 {
-			fmt.Println(color.RedString("[!] No target url set"))
-			return
-		} else if fn, err := identify.TargetURL(args[0]); err != nil {
-			fmt.Println(color.RedString("[!] Could not parse target url: %s", err.Error()))
-			return
-		} else {
-			options = append(options, fn)
-		}
+// IdentifyAction identifies the application of the target url, local path or
+// image.
+func IdentifyAction(c *cli.Context) {
+	write, err := reportWriter(c)
+	if err != nil {
+		fmt.Println(color.RedString("[!] %s", err.Error()))
+		return
+	}
+
+	// the report is written to stdout, unless an output file has been set
+	var console io.Writer = os.Stdout
+	if write != nil && c.GlobalString("output") == "" {
+		console = os.Stderr
+	}
+
+	fmt.Fprintln(console, "Identify - Identify web application versions")
+	fmt.Fprintln(console, "http://github.com/dutchcoders/identify")
+	fmt.Fprintln(console)
+	fmt.Fprintln(console, "DutchSec [https://dutchsec.com/]")
+	fmt.Fprintln(console, "--------------------------------------")
 
-		if proxy := c.GlobalString("proxy"); proxy == "" {
-		} else if fn, err := identify.ProxyURL(proxy); err != nil {
-			fmt.Println(color.RedString("[!] Could find set proxy: %s", err.Error()))
+	options := []identify.OptionFn{}
+
+	if application := c.GlobalString("application"); application == "" {
+		fmt.Fprintln(console, color.RedString("[!] No application set"))
+		return
+	} else if fn, err := identify.TargetApplication(application); err != nil {
+		fmt.Fprintln(console, color.RedString("[!] Could find target application: %s", err.Error()))
+		return
+	} else {
+		options = append(options, fn)
+	}
+
+	if fn, err := globalOptions(c); err != nil {
+		fmt.Fprintln(console, color.RedString("[!] %s", err.Error()))
+		return
+	} else {
+		options = append(options, fn...)
+	}
+
+	// after the proxy, as local paths replace the transport
+	if image := c.GlobalString("image"); image != "" {
+		if fn, err := identify.Image(image); err != nil {
+			fmt.Fprintln(console, color.RedString("[!] Could not use image: %s", err.Error()))
 			return
 		} else {
 			options = append(options, fn)
 		}
-
-		if application := c.GlobalString("application"); application == "" {
-			fmt.Println(color.RedString("[!] No application set"))
-			return
-		} else if fn, err := identify.TargetApplication(application); err != nil {
-			fmt.Println(color.RedString("[!] Could find target application: %s", err.Error()))
+	} else if path := c.GlobalString("path"); path != "" {
+		if fn, err := identify.LocalPath(path); err != nil {
+			fmt.Fprintln(console, color.RedString("[!] Could not use local path: %s", err.Error()))
 			return
 		} else {
 			options = append(options, fn)
 		}
+	} else if args := c.Args(); len(args) == 0 {
+		fmt.Fprintln(console, color.RedString("[!] No target url set"))
+		return
+	} else if fn, err := identify.TargetURL(args[0]); err != nil {
+		fmt.Fprintln(console, color.RedString("[!] Could not parse target url: %s", err.Error()))
+		return
+	} else {
+		options = append(options, fn)
+	}
 
-		if !c.Bool("no-branches") {
-		} else if fn, err := identify.NoBranches(); err != nil {
-		} else {
-			options = append(options, fn)
-		}
+	if bundle := c.GlobalString("bundle"); bundle == "" {
+	} else if fn, err := identify.Bundle(bundle); err != nil {
+		fmt.Fprintln(console, color.RedString("[!] Could not write bundle: %s", err.Error()))
+		return
+	} else {
+		options = append(options, fn)
+	}
 
-		if !c.Bool("no-tags") {
-		} else if fn, err := identify.NoTags(); err != nil {
-		} else {
-			options = append(options, fn)
-		}
+	// after the proxy, as captured responses replace the transport
+	if capture := c.GlobalString("capture"); capture == "" {
+	} else if fn, err := identify.Capture(capture); err != nil {
+		fmt.Fprintln(console, color.RedString("[!] Could not use capture: %s", err.Error()))
+		return
+	} else {
+		options = append(options, fn)
+	}
 
-		if !c.Bool("debug") {
-		} else if fn, err := identify.Debug(); err != nil {
-		} else {
-			options = append(options, fn)
-		}
+	if fn, err := identify.Output(console); err != nil {
+	} else {
+		options = append(options, fn)
+	}
 
-		b, err := identify.New(options...)
-		if err != nil {
-			fmt.Println(color.RedString("[!] Error: %s", err.Error()))
-			return
-		}
+	b, err := identify.New(options...)
+	if err != nil {
+		fmt.Fprintln(console, color.RedString("[!] Error: %s", err.Error()))
+		return
+	}
 
-		if err := b.Identify(); err != nil {
-			fmt.Println(color.RedString("[!] Error identifying application: %s", err.Error()))
-			return
-		}
+	if err := b.Identify(); err != nil {
+		fmt.Fprintln(console, color.RedString("[!] Error identifying application: %s", err.Error()))
+		return
 	}
 
-	return &Cmd{
-		App: app,
+	if write == nil {
+		return
+	}
+
+	if err := writeReport(b.Report(), write, c.GlobalString("output")); err != nil {
+		fmt.Fprintln(console, color.RedString("[!] Error writing report: %s", err.Error()))
+		return
 	}
 }
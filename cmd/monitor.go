@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/minio/cli"
+
+	identify "github.com/dutchcoders/identify/app"
+)
+
+var monitorCommand = cli.Command{
+	Name:  "monitor",
+	Usage: "scan registered targets on a schedule and detect version changes",
+	Subcommands: []cli.Command{
+		{
+			Name:   "add",
+			Usage:  "register a target, using the global application flag",
+			Action: MonitorAddAction,
+			Flags: []cli.Flag{
+				cli.DurationFlag{
+					Name:  "interval",
+					Usage: "interval between scans of the target",
+					Value: 24 * time.Hour,
+				},
+			},
+		},
+		{
+			Name:   "remove",
+			Usage:  "unregister a target, keeping its history",
+			Action: MonitorRemoveAction,
+		},
+		{
+			Name:   "list",
+			Usage:  "list the registered targets",
+			Action: MonitorListAction,
+		},
+		{
+			Name:   "history",
+			Usage:  "show the scans and events of a target",
+			Action: MonitorHistoryAction,
+		},
+		{
+			Name:   "run",
+			Usage:  "scan the registered targets when they are due",
+			Action: MonitorRunAction,
+			Flags: []cli.Flag{
+				cli.DurationFlag{
+					Name:  "refresh",
+					Usage: "update repositories when they are older than this",
+					Value: time.Hour,
+				},
+				cli.StringFlag{
+					Name:  "listen",
+					Usage: "address to serve metrics on",
+					Value: "",
+				},
+			},
+		},
+	},
+}
+
+func openStore() (*identify.Store, error) {
+	cachePath, err := identify.DefaultCachePath()
+	if err != nil {
+		return nil, err
+	}
+
+	return identify.OpenStore(path.Join(cachePath, "monitor"))
+}
+
+func MonitorAddAction(c *cli.Context) {
+	store, err := openStore()
+	if err != nil {
+		fmt.Println(color.RedString("[!] Could not open store: %s", err.Error()))
+		return
+	}
+
+	application := c.GlobalString("application")
+	if application == "" {
+		application = identify.ApplicationAuto
+	}
+
+	for _, url := range c.Args() {
+		if err := store.SaveTarget(&identify.Target{
+			URL:         url,
+			Application: application,
+			Interval:    c.Duration("interval"),
+			Added:       time.Now(),
+		}); err != nil {
+			fmt.Println(color.RedString("[!] Could not add target %s: %s", url, err.Error()))
+			return
+		}
+
+		fmt.Println(color.YellowString("[+] Monitoring %s (%s) every %s", url, application, c.Duration("interval")))
+	}
+}
+
+func MonitorRemoveAction(c *cli.Context) {
+	store, err := openStore()
+	if err != nil {
+		fmt.Println(color.RedString("[!] Could not open store: %s", err.Error()))
+		return
+	}
+
+	for _, url := range c.Args() {
+		if err := store.RemoveTarget(url); err != nil {
+			fmt.Println(color.RedString("[!] Could not remove target %s: %s", url, err.Error()))
+			return
+		}
+
+		fmt.Println(color.YellowString("[+] Removed %s", url))
+	}
+}
+
+func MonitorListAction(c *cli.Context) {
+	store, err := openStore()
+	if err != nil {
+		fmt.Println(color.RedString("[!] Could not open store: %s", err.Error()))
+		return
+	}
+
+	targets, err := store.Targets()
+	if err != nil {
+		fmt.Println(color.RedString("[!] Could not list targets: %s", err.Error()))
+		return
+	}
+
+	for _, target := range targets {
+		lastScan := "never"
+		if !target.LastScan.IsZero() {
+			lastScan = target.LastScan.Format(time.RFC3339)
+		}
+
+		fmt.Printf("%s\t%s\tevery %s\tlast scan %s\n", target.URL, target.Application, target.Interval, lastScan)
+	}
+}
+
+func MonitorHistoryAction(c *cli.Context) {
+	store, err := openStore()
+	if err != nil {
+		fmt.Println(color.RedString("[!] Could not open store: %s", err.Error()))
+		return
+	}
+
+	if len(c.Args()) == 0 {
+		fmt.Println(color.RedString("[!] No target url set"))
+		return
+	}
+
+	url := c.Args()[0]
+
+	scans, err := store.Scans(url)
+	if err != nil {
+		fmt.Println(color.RedString("[!] Could not read scans: %s", err.Error()))
+		return
+	}
+
+	fmt.Println(color.YellowString("[+] Scans of %s", url))
+
+	for _, scan := range scans {
+		if scan.Error != "" {
+			fmt.Println(color.RedString(" |  %s error: %s", scan.Time.Format(time.RFC3339), scan.Error))
+		} else if scan.Version == "" {
+			fmt.Printf(" |  %s not identified\n", scan.Time.Format(time.RFC3339))
+		} else if !scan.Consistent {
+			fmt.Println(color.RedString(" |  %s %s %3.0f%% inconsistent", scan.Time.Format(time.RFC3339), strings.Join(scan.Candidates, ", "), scan.Score*100))
+		} else {
+			fmt.Printf(" |  %s %s %3.0f%%\n", scan.Time.Format(time.RFC3339), strings.Join(scan.Candidates, ", "), scan.Score*100)
+		}
+	}
+
+	events, err := store.Events(url)
+	if err != nil {
+		fmt.Println(color.RedString("[!] Could not read events: %s", err.Error()))
+		return
+	}
+
+	if len(events) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println(color.YellowString("[+] Events of %s", url))
+
+	for _, event := range events {
+		fmt.Printf(" |  %s %s: %s\n", event.Time.Format(time.RFC3339), event.Type, event.Message)
+	}
+}
+
+func MonitorRunAction(c *cli.Context) {
+	store, err := openStore()
+	if err != nil {
+		fmt.Println(color.RedString("[!] Could not open store: %s", err.Error()))
+		return
+	}
+
+	options, err := globalOptions(c)
+	if err != nil {
+		fmt.Println(color.RedString("[!] %s", err.Error()))
+		return
+	}
+
+	metrics := identify.NewMetrics()
+
+	if fn, err := identify.CollectMetrics(metrics); err != nil {
+	} else {
+		options = append(options, fn)
+	}
+
+	if fn, err := identify.SharedIndex(identify.NewIndex(c.Duration("refresh"))); err != nil {
+	} else {
+		options = append(options, fn)
+	}
+
+	if fn, err := identify.Output(ioutil.Discard); err != nil {
+	} else {
+		options = append(options, fn)
+	}
+
+	if listen := c.String("listen"); listen != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics)
+
+		go func() {
+			if err := http.ListenAndServe(listen, mux); err != nil {
+				fmt.Println(color.RedString("[!] Error serving metrics: %s", err.Error()))
+			}
+		}()
+	}
+
+	monitor := identify.NewMonitor(store, os.Stdout, options...)
+
+	if hooks, err := globalHooks(c); err != nil {
+		fmt.Println(color.RedString("[!] %s", err.Error()))
+		return
+	} else if hooks != nil {
+		monitor.OnEvent(func(event *identify.Event, report *identify.Report) {
+			hooks.NotifyEvent(os.Stdout, event, report)
+		})
+	}
+
+	fmt.Println(color.YellowString("[+] Monitoring targets"))
+
+	if err := monitor.Run(context.Background()); err != nil {
+		fmt.Println(color.RedString("[!] Error monitoring: %s", err.Error()))
+		return
+	}
+}
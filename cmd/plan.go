@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/minio/cli"
+
+	identify "github.com/dutchcoders/identify/app"
+)
+
+var planFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "application",
+		Usage: "the application to identify, overrides the global flag",
+		Value: "",
+	},
+}
+
+// commandApplication returns the application of the command flag, or the
+// global flag when it hasn't been set.
+func commandApplication(c *cli.Context) string {
+	if application := c.String("application"); application != "" {
+		return application
+	}
+
+	return c.GlobalString("application")
+}
+
+// PlanAction writes the urls that will be requested to identify the target,
+// one per line, without requesting them.
+func PlanAction(c *cli.Context) {
+	options := []identify.OptionFn{}
+
+	if args := c.Args(); len(args) == 0 {
+		fmt.Fprintln(os.Stderr, color.RedString("[!] No target url set"))
+		return
+	} else if fn, err := identify.TargetURL(args[0]); err != nil {
+		fmt.Fprintln(os.Stderr, color.RedString("[!] Could not parse target url: %s", err.Error()))
+		return
+	} else {
+		options = append(options, fn)
+	}
+
+	if application := commandApplication(c); application == "" {
+		fmt.Fprintln(os.Stderr, color.RedString("[!] No application set"))
+		return
+	} else if fn, err := identify.TargetApplication(application); err != nil {
+		fmt.Fprintln(os.Stderr, color.RedString("[!] Could not find target application: %s", err.Error()))
+		return
+	} else {
+		options = append(options, fn)
+	}
+
+	if fn, err := globalOptions(c); err != nil {
+		fmt.Fprintln(os.Stderr, color.RedString("[!] %s", err.Error()))
+		return
+	} else {
+		options = append(options, fn...)
+	}
+
+	if fn, err := identify.Output(ioutil.Discard); err != nil {
+	} else {
+		options = append(options, fn)
+	}
+
+	b, err := identify.New(options...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, color.RedString("[!] Error: %s", err.Error()))
+		return
+	}
+
+	urls, err := b.Plan()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, color.RedString("[!] Could not plan: %s", err.Error()))
+		return
+	}
+
+	var w io.Writer = os.Stdout
+	if output := c.GlobalString("output"); output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, color.RedString("[!] Could not create output: %s", err.Error()))
+			return
+		}
+
+		defer f.Close()
+
+		w = f
+	}
+
+	for _, u := range urls {
+		fmt.Fprintln(w, u)
+	}
+}
+
+// IngestAction identifies the target from a directory of fetched responses,
+// without any network access.
+func IngestAction(c *cli.Context) {
+	if len(c.Args()) < 2 {
+		fmt.Fprintln(os.Stderr, color.RedString("[!] Usage: identify ingest [--application name] url directory"))
+		return
+	}
+
+	if err := c.GlobalSet("application", commandApplication(c)); err != nil {
+		fmt.Fprintln(os.Stderr, color.RedString("[!] %s", err.Error()))
+		return
+	} else if err := c.GlobalSet("capture", c.Args()[1]); err != nil {
+		fmt.Fprintln(os.Stderr, color.RedString("[!] %s", err.Error()))
+		return
+	}
+
+	IdentifyAction(c)
+}
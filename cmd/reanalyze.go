@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/minio/cli"
+
+	identify "github.com/dutchcoders/identify/app"
+)
+
+// ReanalyzeAction identifies the targets again from the latest responses
+// stored in the evidence directory, with the current database and
+// repositories, without requesting the targets.
+func ReanalyzeAction(c *cli.Context) {
+	write, err := reportWriter(c)
+	if err != nil {
+		fmt.Println(color.RedString("[!] %s", err.Error()))
+		return
+	}
+
+	var console io.Writer = os.Stdout
+	if write != nil && c.GlobalString("output") == "" {
+		console = os.Stderr
+	}
+
+	path := c.GlobalString("evidence")
+	if path == "" {
+		fmt.Fprintln(console, color.RedString("[!] No evidence directory set"))
+		return
+	}
+
+	evidence, err := identify.OpenEvidence(path)
+	if err != nil {
+		fmt.Fprintln(console, color.RedString("[!] Could not open evidence directory: %s", err.Error()))
+		return
+	}
+
+	scans := []*identify.EvidenceScan{}
+
+	if len(c.Args()) == 0 {
+		if scans, err = evidence.Latest(); err != nil {
+			fmt.Fprintln(console, color.RedString("[!] Could not read evidence: %s", err.Error()))
+			return
+		}
+	}
+
+	for _, url := range c.Args() {
+		if stored, err := evidence.Scans(url); err != nil {
+			fmt.Fprintln(console, color.RedString("[!] Could not read evidence: %s", err.Error()))
+			return
+		} else if len(stored) == 0 {
+			fmt.Fprintln(console, color.RedString("[!] No evidence stored for %s", url))
+			return
+		} else {
+			scans = append(scans, stored[len(stored)-1])
+		}
+	}
+
+	if len(scans) > 1 && write != nil && c.GlobalString("output") != "" {
+		fmt.Fprintln(console, color.RedString("[!] Writing the report to a file requires a single target"))
+		return
+	}
+
+	for _, scan := range scans {
+		options := []identify.OptionFn{}
+
+		if fn, err := identify.TargetURL(scan.URL); err != nil {
+			fmt.Fprintln(console, color.RedString("[!] Could not parse target url: %s", err.Error()))
+			continue
+		} else {
+			options = append(options, fn)
+		}
+
+		application := commandApplication(c)
+		if application == "" {
+			application = scan.Application
+		}
+
+		if fn, err := identify.TargetApplication(application); err != nil {
+		} else {
+			options = append(options, fn)
+		}
+
+		if fn, err := globalOptions(c); err != nil {
+			fmt.Fprintln(console, color.RedString("[!] %s", err.Error()))
+			return
+		} else {
+			options = append(options, fn...)
+		}
+
+		// the stored responses are analyzed, not stored again
+		if fn, err := identify.StoreEvidence(""); err != nil {
+		} else {
+			options = append(options, fn)
+		}
+
+		if fn, err := identify.NotFoundProbes(scan.Probes); err != nil {
+		} else {
+			options = append(options, fn)
+		}
+
+		if fn, err := identify.Capture(scan.Responses); err != nil {
+			fmt.Fprintln(console, color.RedString("[!] Could not read evidence of %s: %s", scan.URL, err.Error()))
+			continue
+		} else {
+			options = append(options, fn)
+		}
+
+		if fn, err := identify.Output(console); err != nil {
+		} else {
+			options = append(options, fn)
+		}
+
+		fmt.Fprintln(console, color.YellowString("[+] Reanalyzing %s, scanned %s", scan.URL, scan.Time.Format("2006-01-02 15:04:05")))
+
+		b, err := identify.New(options...)
+		if err != nil {
+			fmt.Fprintln(console, color.RedString("[!] Error: %s", err.Error()))
+			continue
+		}
+
+		if err := b.Identify(); err != nil {
+			fmt.Fprintln(console, color.RedString("[!] Error identifying application: %s", err.Error()))
+			continue
+		}
+
+		if write == nil {
+		} else if err := writeReport(b.Report(), write, c.GlobalString("output")); err != nil {
+			fmt.Fprintln(console, color.RedString("[!] Error writing report: %s", err.Error()))
+		}
+
+		fmt.Fprintln(console)
+	}
+}
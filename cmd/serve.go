@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/minio/cli"
+
+	identify "github.com/dutchcoders/identify/app"
+)
+
+var serveFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "listen",
+		Usage: "address to listen on",
+		Value: "127.0.0.1:8080",
+	},
+	cli.IntFlag{
+		Name:  "concurrency",
+		Usage: "maximum number of concurrent identifications",
+		Value: 4,
+	},
+	cli.DurationFlag{
+		Name:  "refresh",
+		Usage: "update repositories when they are older than this",
+		Value: time.Hour,
+	},
+	cli.StringFlag{
+		Name:   "token",
+		Usage:  "require this bearer token for api requests",
+		EnvVar: "IDENTIFY_TOKEN",
+	},
+}
+
+func ServeAction(c *cli.Context) {
+	options, err := globalOptions(c)
+	if err != nil {
+		fmt.Println(color.RedString("[!] %s", err.Error()))
+		return
+	}
+
+	metrics := identify.NewMetrics()
+
+	if fn, err := identify.CollectMetrics(metrics); err != nil {
+	} else {
+		options = append(options, fn)
+	}
+
+	index := identify.NewIndex(c.Duration("refresh"))
+
+	server, err := identify.NewServer(index, c.Int("concurrency"), options...)
+	if err != nil {
+		fmt.Println(color.RedString("[!] Could not create server: %s", err.Error()))
+		return
+	}
+
+	if token := c.String("token"); token != "" {
+		server.RequireToken(token)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics)
+	mux.Handle("/", server)
+
+	fmt.Println(color.YellowString("[+] Listening on http://%s/", c.String("listen")))
+
+	if err := http.ListenAndServe(c.String("listen"), mux); err != nil {
+		fmt.Println(color.RedString("[!] Error serving: %s", err.Error()))
+		return
+	}
+}